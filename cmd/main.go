@@ -5,48 +5,219 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
 	"messenger/internal/adapters/handlers"
+	"messenger/internal/adapters/imaging"
+	"messenger/internal/adapters/presence"
+	"messenger/internal/adapters/realtime"
 	"messenger/internal/adapters/repositories"
+	"messenger/internal/adapters/storage"
+	"messenger/internal/adapters/unfurl"
+	"messenger/internal/adapters/webhook"
+	"messenger/internal/core/ports"
 	"messenger/internal/core/services"
 )
 
 var (
 	repo                 = flag.String("db", "mongo", "Database for storing messages")
+	blobStore            = flag.String("storage", "local", "Blob storage for attachments (local or s3)")
+	unfurler             = flag.String("unfurler", "http", "Link preview fetcher (http or fake)")
 	httpHandlerMessanger *handlers.HTTPHandlerMessanger
 	svcMessanger         *services.MessangerService
 	HTTPHandlerUser      *handlers.HTTPHandlerUser
 	svcUser              *services.UserService
+	svcConversation      *services.ConversationService
+	svcRead              *services.ReadService
+	svcPreview           *services.PreviewService
+	svcPresence          *services.PresenceService
+	svcUnfurl            *services.UnfurlService
+	svcWebhook           *services.WebhookService
+	hub                  = realtime.NewHub()
+	presenceStore        = presence.NewMemoryPresenceStore()
 )
 
 func main() {
 	flag.Parse()
 
 	fmt.Printf("Application running using %s\n", *repo)
+	blobs := newBlobStorage()
+
 	switch *repo {
 	case "mongo":
+		storeConversation := repositories.NewConversationMongoRepository()
+		storeReaction := repositories.NewReactionMongoRepository()
+		storeRead := repositories.NewReadMarkerMongoRepository()
+		storeRevision := repositories.NewRevisionMongoRepository()
+		storeAttachment := repositories.NewAttachmentMongoRepository()
+		storeMention := repositories.NewMentionMongoRepository()
+		storePin := repositories.NewPinMongoRepository()
+		storeStar := repositories.NewStarMongoRepository()
+		storeScheduled := repositories.NewScheduledMessageMongoRepository()
+		storePoll := repositories.NewPollMongoRepository()
+		storeLinkPreview := repositories.NewLinkPreviewMongoRepository()
+		storeDraft := repositories.NewDraftMongoRepository()
+		storeReceipt := repositories.NewReceiptMongoRepository()
+		storeIncoming := repositories.NewIncomingWebhookMongoRepository()
 		storeMessanger := repositories.NewMessangerMongoRepository()
-		svcMessanger = services.NewMessangerService(storeMessanger)
 		storeUser := repositories.NewUserMongoRepository()
+		svcMessanger = services.NewMessangerService(storeMessanger, storeConversation, storeReaction, storeRevision, storeAttachment, storeMention, storePin, storeStar, storeScheduled, storePoll, storeLinkPreview, storeDraft, storeReceipt, storeRead, storeIncoming, storeUser, blobs, hub)
+		svcPreview = services.NewPreviewService(storeAttachment, blobs, imaging.NewImageThumbnailer(imaging.DefaultMaxPixels), storeConversation, hub)
+		svcUnfurl = services.NewUnfurlService(storeLinkPreview, newUnfurler(), storeMessanger, storeConversation, hub)
 		svcUser = services.NewUserService(storeUser)
+		svcConversation = services.NewConversationService(storeConversation, storeUser)
+		svcRead = services.NewReadService(storeRead, storeMessanger, storeConversation, hub)
+		svcPresence = services.NewPresenceService(presenceStore, storeUser, storeConversation, hub)
+		svcWebhook = services.NewWebhookService(repositories.NewWebhookMongoRepository(), webhook.NewHTTPWebhookSender(durationEnv("WEBHOOK_TIMEOUT", webhook.DefaultTimeout)))
 	default:
+		storeConversation := repositories.NewConversationPostgresRepository()
+		storeReaction := repositories.NewReactionPostgresRepository()
+		storeRead := repositories.NewReadMarkerPostgresRepository()
+		storeRevision := repositories.NewRevisionPostgresRepository()
+		storeAttachment := repositories.NewAttachmentPostgresRepository()
+		storeMention := repositories.NewMentionPostgresRepository()
+		storePin := repositories.NewPinPostgresRepository()
+		storeStar := repositories.NewStarPostgresRepository()
+		storeScheduled := repositories.NewScheduledMessagePostgresRepository()
+		storePoll := repositories.NewPollPostgresRepository()
+		storeLinkPreview := repositories.NewLinkPreviewPostgresRepository()
+		storeDraft := repositories.NewDraftPostgresRepository()
+		storeReceipt := repositories.NewReceiptPostgresRepository()
+		storeIncoming := repositories.NewIncomingWebhookPostgresRepository()
 		storeMessanger := repositories.NewMessangerPostgresRepository()
-		svcMessanger = services.NewMessangerService(storeMessanger)
 		storeUser := repositories.NewUserPostgresRepository()
+		svcMessanger = services.NewMessangerService(storeMessanger, storeConversation, storeReaction, storeRevision, storeAttachment, storeMention, storePin, storeStar, storeScheduled, storePoll, storeLinkPreview, storeDraft, storeReceipt, storeRead, storeIncoming, storeUser, blobs, hub)
+		svcPreview = services.NewPreviewService(storeAttachment, blobs, imaging.NewImageThumbnailer(imaging.DefaultMaxPixels), storeConversation, hub)
+		svcUnfurl = services.NewUnfurlService(storeLinkPreview, newUnfurler(), storeMessanger, storeConversation, hub)
 		svcUser = services.NewUserService(storeUser)
+		svcConversation = services.NewConversationService(storeConversation, storeUser)
+		svcRead = services.NewReadService(storeRead, storeMessanger, storeConversation, hub)
+		svcPresence = services.NewPresenceService(presenceStore, storeUser, storeConversation, hub)
+		svcWebhook = services.NewWebhookService(repositories.NewWebhookPostgresRepository(), webhook.NewHTTPWebhookSender(durationEnv("WEBHOOK_TIMEOUT", webhook.DefaultTimeout)))
 	}
 
+	svcMessanger.SetDeletePolicy(
+		durationEnv("MESSAGE_RESTORE_WINDOW", services.DefaultRestoreWindow),
+		durationEnv("MESSAGE_RETENTION", services.DefaultRetention),
+	)
+	svcMessanger.SetPreviews(svcPreview)
+	svcMessanger.SetUnfurls(svcUnfurl)
+	svcMessanger.SetWebhooks(svcWebhook)
+	svcMessanger.SetIncomingRateLimit(
+		intEnv("INCOMING_WEBHOOK_RATE_LIMIT", services.DefaultIncomingRateLimit),
+		durationEnv("INCOMING_WEBHOOK_RATE_WINDOW", services.DefaultIncomingRateWindow),
+	)
+	svcUser.SetWebhooks(svcWebhook)
+	svcWebhook.SetAdmins(listEnv("ADMIN_USER_IDS"))
+	svcWebhook.SetRetryPolicy(
+		intEnv("WEBHOOK_MAX_ATTEMPTS", services.DefaultWebhookAttempts),
+		durationEnv("WEBHOOK_BACKOFF", services.DefaultWebhookBackoff),
+	)
+	svcWebhook.SetRetention(durationEnv("WEBHOOK_RETENTION", services.DefaultWebhookRetention))
+	svcPreview.Start(intEnv("PREVIEW_WORKERS", 4))
+	svcUnfurl.Start(intEnv("UNFURL_WORKERS", 4))
+	svcMessanger.StartPurge(durationEnv("MESSAGE_PURGE_INTERVAL", time.Hour))
+	svcMessanger.StartExpiry(durationEnv("MESSAGE_EXPIRY_INTERVAL", 30*time.Second))
+	svcMessanger.StartScheduler(durationEnv("SCHEDULER_INTERVAL", 5*time.Second))
+	svcPresence.SetExpiry(
+		durationEnv("PRESENCE_TTL", services.DefaultPresenceTtl),
+		durationEnv("TYPING_TTL", services.DefaultTypingTtl),
+	)
+	svcPresence.StartSweeper(durationEnv("PRESENCE_SWEEP_INTERVAL", 2*time.Second))
+	svcWebhook.StartDispatcher(durationEnv("WEBHOOK_DISPATCH_INTERVAL", 2*time.Second))
+	svcWebhook.StartPruner(durationEnv("WEBHOOK_PRUNE_INTERVAL", time.Hour))
+
 	InitRoutes()
 }
 
+func newBlobStorage() ports.BlobStorage {
+	_ = godotenv.Load(".env")
+
+	switch *blobStore {
+	case "s3":
+		blobs, err := storage.NewS3BlobStorage(
+			os.Getenv("S3_ENDPOINT"),
+			os.Getenv("S3_REGION"),
+			os.Getenv("S3_BUCKET"),
+			os.Getenv("S3_ACCESS_KEY"),
+			os.Getenv("S3_SECRET_KEY"),
+		)
+		if err != nil {
+			log.Fatalf("Storage error: %v", err)
+		}
+		return blobs
+	default:
+		root := os.Getenv("STORAGE_PATH")
+		if root == "" {
+			root = "./data/attachments"
+		}
+		return storage.NewLocalBlobStorage(root)
+	}
+}
+
+func newUnfurler() ports.Unfurler {
+	switch *unfurler {
+	case "fake":
+		return unfurl.NewFakeUnfurler(nil)
+	default:
+		return unfurl.NewHTTPUnfurler(
+			durationEnv("UNFURL_TIMEOUT", unfurl.DefaultTimeout),
+			int64(intEnv("UNFURL_MAX_BYTES", unfurl.DefaultMaxBytes)),
+		)
+	}
+}
+
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	_ = godotenv.Load(".env")
+
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func intEnv(key string, fallback int) int {
+	_ = godotenv.Load(".env")
+
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func listEnv(key string) []string {
+	_ = godotenv.Load(".env")
+
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func InitRoutes() {
 	router := gin.Default()
 	handlerMessanger := handlers.NewHTTPHandlerMessanger(*svcMessanger)
 	handlerUser := handlers.NewHTTPHandlerUser(*svcUser)
+	handlerConversation := handlers.NewHTTPHandlerConversation(*svcConversation)
+	handlerRead := handlers.NewHTTPHandlerRead(*svcRead)
+	handlerPresence := handlers.NewHTTPHandlerPresence(*svcPresence)
+	handlerWebSocket := handlers.NewHTTPHandlerWebSocket(hub, svcPresence, listEnv("WS_ALLOWED_ORIGINS"))
+	handlerEvents := handlers.NewHTTPHandlerEvents(hub, svcPresence)
+	handlerWebhook := handlers.NewHTTPHandlerWebhook(*svcWebhook)
 
 	router.GET("/users/export-data", handlerUser.GetAllUsersByExportData)
 	router.GET("/users", handlerUser.GetAllUsers)
+	router.GET("/users/presence", handlerPresence.GetPresences)
 	router.GET("/user/:id", handlerUser.GetOneUser)
 	router.PUT("/user/:id", handlerUser.UpdateUser)
 	router.DELETE("/user/:id", handlerUser.DeleteUser)
@@ -54,10 +225,68 @@ func InitRoutes() {
 	router.POST("/login", handlerUser.LoginUser)
 
 	router.GET("/messages", handlerMessanger.GetAllMessages)
+	router.GET("/messages/search", handlerMessanger.SearchMessages)
+	router.GET("/messages/scheduled", handlerMessanger.GetScheduledMessages)
+	router.PUT("/messages/scheduled/:id", handlerMessanger.UpdateScheduledMessage)
+	router.DELETE("/messages/scheduled/:id", handlerMessanger.CancelScheduledMessage)
 	router.GET("/message/:id", handlerMessanger.GetOneMessage)
+	router.GET("/message/:id/replies", handlerMessanger.GetReplies)
+	router.GET("/message/:id/history", handlerMessanger.GetHistory)
+	router.GET("/message/:id/receipts", handlerMessanger.GetReceipts)
+	router.POST("/message/:id/reactions", handlerMessanger.AddReaction)
+	router.DELETE("/message/:id/reactions/:emoji", handlerMessanger.RemoveReaction)
 	router.POST("/messages", handlerMessanger.CreateMessage)
 	router.PUT("/message/:id", handlerMessanger.UpdateMessage)
 	router.DELETE("/message/:id", handlerMessanger.DeleteMessage)
+	router.POST("/message/:id/restore", handlerMessanger.RestoreMessage)
+	router.POST("/message/:id/forward", handlerMessanger.ForwardMessage)
+	router.POST("/message/:id/pin", handlerMessanger.PinMessage)
+	router.DELETE("/message/:id/pin", handlerMessanger.UnpinMessage)
+	router.PUT("/message/:id/star", handlerMessanger.StarMessage)
+	router.PUT("/message/:id/vote", handlerMessanger.Vote)
+	router.DELETE("/message/:id/vote", handlerMessanger.RetractVote)
+	router.POST("/message/:id/poll/close", handlerMessanger.ClosePoll)
+	router.DELETE("/message/:id/star", handlerMessanger.UnstarMessage)
+	router.GET("/attachments/:id", handlerMessanger.GetAttachment)
+	router.GET("/attachments/:id/thumbnails/:size", handlerMessanger.GetThumbnail)
+
+	router.GET("/ws", handlerWebSocket.Connect)
+	router.GET("/events", handlerEvents.Stream)
+
+	router.POST("/conversations", handlerConversation.CreateConversation)
+	router.GET("/conversations", handlerConversation.GetUserConversations)
+	router.GET("/conversations/:id", handlerConversation.GetOneConversation)
+	router.GET("/conversations/:id/members", handlerConversation.GetMembers)
+	router.POST("/conversations/:id/members", handlerConversation.AddMember)
+	router.DELETE("/conversations/:id/members/:user_id", handlerConversation.RemoveMember)
+	router.PUT("/conversations/:id/delivered", handlerMessanger.MarkDelivered)
+	router.PUT("/conversations/:id/read", handlerRead.MarkRead)
+	router.GET("/conversations/:id/read", handlerRead.GetReadMarkers)
+	router.GET("/conversations/:id/pins", handlerMessanger.GetPins)
+	router.POST("/conversations/:id/typing", handlerPresence.StartTyping)
+	router.DELETE("/conversations/:id/typing", handlerPresence.StopTyping)
+	router.GET("/conversations/:id/typing", handlerPresence.GetTyping)
+	router.PUT("/conversations/:id/draft", handlerMessanger.SaveDraft)
+	router.GET("/conversations/:id/draft", handlerMessanger.GetDraft)
+	router.DELETE("/conversations/:id/draft", handlerMessanger.DeleteDraft)
+	router.POST("/conversations/:id/incoming-webhooks", handlerMessanger.CreateIncomingWebhook)
+	router.GET("/conversations/:id/incoming-webhooks", handlerMessanger.GetIncomingWebhooks)
+	router.POST("/conversations/:id/incoming-webhooks/:hook_id/rotate", handlerMessanger.RotateIncomingWebhook)
+	router.DELETE("/conversations/:id/incoming-webhooks/:hook_id", handlerMessanger.DeleteIncomingWebhook)
+
+	router.PUT("/me/presence", handlerPresence.SetPresence)
+	router.GET("/me/unread", handlerRead.GetUnreadCounts)
+	router.GET("/me/starred", handlerMessanger.GetStarred)
+	router.GET("/me/drafts", handlerMessanger.GetDrafts)
+	router.GET("/mentions", handlerMessanger.GetMentions)
+
+	router.POST("/hooks/:token", handlerMessanger.PostIncoming)
+
+	router.POST("/webhooks", handlerWebhook.CreateWebhook)
+	router.GET("/webhooks", handlerWebhook.GetWebhooks)
+	router.DELETE("/webhooks/:id", handlerWebhook.DeleteWebhook)
+	router.GET("/webhooks/:id/deliveries", handlerWebhook.GetDeliveries)
+	router.POST("/webhooks/:id/deliveries/:delivery_id/retry", handlerWebhook.RetryDelivery)
 
 	port := "5000"
 
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"messenger/internal/core/domain"
+	"messenger/internal/core/services"
+)
+
+type HTTPHandlerConversation struct {
+	svc services.ConversationService
+}
+
+type conversationRequest struct {
+	Type      string   `json:"type"`
+	Name      string   `json:"name"`
+	UserId    string   `json:"user_id"`
+	MemberIds []string `json:"member_ids"`
+}
+
+type memberRequest struct {
+	UserId string `json:"user_id"`
+}
+
+func NewHTTPHandlerConversation(ConversationService services.ConversationService) *HTTPHandlerConversation {
+	return &HTTPHandlerConversation{
+		svc: ConversationService,
+	}
+}
+
+func (h *HTTPHandlerConversation) CreateConversation(ctx *gin.Context) {
+	var request conversationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	var conversation *domain.Conversation
+	var err error
+	switch request.Type {
+	case domain.ConversationDirect:
+		conversation, err = h.svc.CreateDirectConversation(userID, request.UserId)
+	case domain.ConversationGroup:
+		conversation, err = h.svc.CreateGroupConversation(userID, request.Name, request.MemberIds)
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": "type must be direct or group",
+		})
+		return
+	}
+
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, conversation)
+}
+
+func (h *HTTPHandlerConversation) GetUserConversations(ctx *gin.Context) {
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	conversations, err := h.svc.GetUserConversations(userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, conversations)
+}
+
+func (h *HTTPHandlerConversation) GetOneConversation(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	conversation, err := h.svc.GetOneConversation(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, conversation)
+}
+
+func (h *HTTPHandlerConversation) GetMembers(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	members, err := h.svc.GetMembers(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, members)
+}
+
+func (h *HTTPHandlerConversation) AddMember(ctx *gin.Context) {
+	var request memberRequest
+
+	id := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svc.AddMember(userID, id, request.UserId)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Member added successfully",
+	})
+}
+
+func (h *HTTPHandlerConversation) RemoveMember(ctx *gin.Context) {
+	id := ctx.Param("id")
+	memberID := ctx.Param("user_id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svc.RemoveMember(userID, id, memberID)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Member removed successfully",
+	})
+}
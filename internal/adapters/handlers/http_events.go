@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"messenger/internal/adapters/realtime"
+	"messenger/internal/core/services"
+)
+
+const heartbeatPeriod = 30 * time.Second
+
+type HTTPHandlerEvents struct {
+	hub      *realtime.Hub
+	presence *services.PresenceService
+}
+
+func NewHTTPHandlerEvents(hub *realtime.Hub, presence *services.PresenceService) *HTTPHandlerEvents {
+	return &HTTPHandlerEvents{
+		hub:      hub,
+		presence: presence,
+	}
+}
+
+func (h *HTTPHandlerEvents) Stream(ctx *gin.Context) {
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	lastEventID := ctx.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = ctx.Query("last_event_id")
+	}
+
+	sessionID := ctx.Query("session_id")
+	if sessionID == "" {
+		sessionID = uuid.New().String()
+	}
+
+	client, missed := h.hub.Subscribe(userID, lastEventID)
+	defer h.hub.Unregister(client)
+
+	h.heartbeat(userID, sessionID)
+	defer func() {
+		if err := h.presence.Disconnect(userID, sessionID); err != nil {
+			log.Printf("presence of %s: %v", userID, err)
+		}
+	}()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	for _, event := range missed {
+		if err := sse.Encode(ctx.Writer, sse.Event{Id: event.Id, Event: event.Type, Data: event}); err != nil {
+			return
+		}
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatPeriod)
+	defer heartbeat.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case event, ok := <-client.Send:
+			if !ok {
+				return false
+			}
+			return sse.Encode(w, sse.Event{Id: event.Id, Event: event.Type, Data: event}) == nil
+		case <-heartbeat.C:
+			h.heartbeat(userID, sessionID)
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+func (h *HTTPHandlerEvents) heartbeat(userID, sessionID string) {
+	if err := h.presence.Heartbeat(userID, sessionID); err != nil {
+		log.Printf("presence of %s: %v", userID, err)
+	}
+}
@@ -3,8 +3,12 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +18,46 @@ import (
 	"messenger/internal/core/services"
 )
 
+const defaultAttachmentMaxSize = 25 << 20
+
+type scheduledRequest struct {
+	Body   string     `json:"body"`
+	SendAt *time.Time `json:"send_at"`
+}
+
+type forwardRequest struct {
+	ConversationId string `json:"conversation_id" binding:"required"`
+}
+
+type starRequest struct {
+	Note string `json:"note"`
+}
+
+type draftRequest struct {
+	Body     string `json:"body"`
+	ParentId string `json:"parent_id"`
+	DeviceId string `json:"device_id"`
+}
+
+type deliveredRequest struct {
+	MessageId string `json:"message_id" binding:"required"`
+}
+
+type incomingWebhookRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// incomingMessageRequest also accepts the "text" field most chat integrations send.
+type incomingMessageRequest struct {
+	Body     string `json:"body"`
+	Text     string `json:"text"`
+	ParentId string `json:"parent_id"`
+}
+
+type voteRequest struct {
+	OptionIds []string `json:"option_ids" binding:"required"`
+}
+
 type HTTPHandlerMessanger struct {
 	svcMessanger services.MessangerService
 }
@@ -26,49 +70,149 @@ func NewHTTPHandlerMessanger(MessangerService services.MessangerService) *HTTPHa
 
 func (h *HTTPHandlerMessanger) CreateMessage(ctx *gin.Context) {
 	var message domain.Message
-	if err := ctx.ShouldBindJSON(&message); err != nil {
+	var uploads []domain.Upload
+
+	// authenticate first, so anonymous requests never get their uploads spooled to disk
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	if strings.HasPrefix(ctx.ContentType(), "multipart/form-data") {
+		form, err := bindMultipartMessage(ctx, &message)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"Error": err.Error(),
+			})
+			return
+		}
+		defer form.RemoveAll()
+
+		for _, header := range form.File["files"] {
+			file, err := header.Open()
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"Error": err.Error(),
+				})
+				return
+			}
+			defer file.Close()
+
+			uploads = append(uploads, domain.Upload{
+				FileName: header.Filename,
+				Size:     header.Size,
+				Content:  file,
+			})
+		}
+	} else if err := ctx.ShouldBindJSON(&message); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"Error": err,
 		})
 		return
 	}
-	err := godotenv.Load(".env")
 
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"Error": err,
+	if message.SendAt != nil {
+		if len(uploads) > 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"Error": "attachments cannot be scheduled",
+			})
+			return
+		}
+
+		scheduled, err := h.svcMessanger.ScheduleMessage(userID, message)
+		if err != nil {
+			ctx.JSON(errorStatus(err), gin.H{
+				"Error": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusAccepted, gin.H{
+			"message": "Message scheduled successfully",
+			"data":    scheduled,
 		})
 		return
 	}
 
-	JWTSecret := os.Getenv("SECRET_JWT")
+	created, err := h.svcMessanger.CreateMessageWithAttachments(userID, message, uploads)
 
-	userID, err := ValidateToken(ctx.Request.Header.Get("Authorization"), JWTSecret)
 	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "New message created successfully",
+		"data":    created,
+	})
+}
+
+func (h *HTTPHandlerMessanger) ForwardMessage(ctx *gin.Context) {
+	var request forwardRequest
+
+	id := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
-			"Error": "user not authorization",
+			"Error": err.Error(),
 		})
 		return
 	}
 
-	err = h.svcMessanger.CreateMessage(userID, message)
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
 
+	message, err := h.svcMessanger.ForwardMessage(userID, id, request.ConversationId)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"Error": err,
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
 		})
 		return
 	}
+
 	ctx.JSON(http.StatusCreated, gin.H{
-		"message": "New message created successfully",
+		"message": "Message forwarded successfully",
+		"data":    message,
+	})
+}
+
+func (h *HTTPHandlerMessanger) GetAttachment(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	attachment, content, err := h.svcMessanger.GetAttachment(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+	defer content.Close()
+
+	ctx.DataFromReader(http.StatusOK, attachment.Size, attachment.MimeType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"ETag":                   strconv.Quote(attachment.Checksum),
 	})
 }
 
 func (h *HTTPHandlerMessanger) GetOneMessage(ctx *gin.Context) {
 	id := ctx.Param("id")
-	message, err := h.svcMessanger.GetOneMessage(id)
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	message, err := h.svcMessanger.GetOneMessage(userID, id)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
+		ctx.JSON(errorStatus(err), gin.H{
 			"Error": err.Error(),
 		})
 		return
@@ -78,7 +222,144 @@ func (h *HTTPHandlerMessanger) GetOneMessage(ctx *gin.Context) {
 }
 
 func (h *HTTPHandlerMessanger) GetAllMessages(ctx *gin.Context) {
-	messages, err := h.svcMessanger.GetAllMessages()
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	query, err := bindPageQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	filter := domain.MessageFilter{
+		PageQuery:      query,
+		ConversationId: ctx.Query("conversation_id"),
+		UserId:         ctx.Query("user_id"),
+	}
+
+	if filter.Since, err = bindTime(ctx, "since"); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	if filter.Until, err = bindTime(ctx, "until"); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	messages, err := h.svcMessanger.GetAllMessages(userID, filter)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, messages)
+}
+
+func (h *HTTPHandlerMessanger) GetReplies(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	query, err := bindPageQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	replies, err := h.svcMessanger.GetReplies(userID, id, query)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, replies)
+}
+
+func (h *HTTPHandlerMessanger) AddReaction(ctx *gin.Context) {
+	var reaction domain.Reaction
+
+	id := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&reaction); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svcMessanger.AddReaction(userID, id, reaction.Emoji)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Reaction added successfully",
+	})
+}
+
+func (h *HTTPHandlerMessanger) RemoveReaction(ctx *gin.Context) {
+	id := ctx.Param("id")
+	emoji := ctx.Param("emoji")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svcMessanger.RemoveReaction(userID, id, emoji)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Reaction removed successfully",
+	})
+}
+
+func (h *HTTPHandlerMessanger) SearchMessages(ctx *gin.Context) {
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	// results are ranked, so they page forward with their own offset cursor
+	if ctx.Query("before") != "" || ctx.Query("after") != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": "search pages with cursor, not before or after",
+		})
+		return
+	}
+
+	query, err := bindPageQuery(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"Error": err.Error(),
@@ -86,7 +367,15 @@ func (h *HTTPHandlerMessanger) GetAllMessages(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, messages)
+	results, err := h.svcMessanger.SearchMessages(userID, ctx.Query("conversation_id"), ctx.Query("q"), ctx.Query("cursor"), query.Limit)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, results)
 }
 
 func (h *HTTPHandlerMessanger) UpdateMessage(ctx *gin.Context) {
@@ -102,7 +391,12 @@ func (h *HTTPHandlerMessanger) UpdateMessage(ctx *gin.Context) {
 		return
 	}
 
-	err := godotenv.Load(".env")
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	messageUpdate, err := h.svcMessanger.UpdateMessage(id, message.Body, userID)
 
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
@@ -111,18 +405,44 @@ func (h *HTTPHandlerMessanger) UpdateMessage(ctx *gin.Context) {
 		return
 	}
 
-	JWTSecret := os.Getenv("SECRET_JWT")
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":   "Message successful updated",
+		"id":        messageUpdate.Id,
+		"body":      messageUpdate.Body,
+		"user_id":   messageUpdate.UserId,
+		"edited":    messageUpdate.Edited,
+		"edited_at": messageUpdate.EditedAt,
+	})
+}
 
-	userID, err := ValidateToken(ctx.Request.Header.Get("Authorization"), JWTSecret)
+func (h *HTTPHandlerMessanger) GetHistory(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.svcMessanger.GetHistory(userID, id)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"Error": "user not authorization",
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
 		})
 		return
 	}
 
-	messageUpdate, err := h.svcMessanger.UpdateMessage(id, message.Body, userID)
+	ctx.JSON(http.StatusOK, revisions)
+}
+
+func (h *HTTPHandlerMessanger) DeleteMessage(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
 
+	err := h.svcMessanger.DeleteMessage(id, userID)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"Error": err.Error(),
@@ -131,23 +451,37 @@ func (h *HTTPHandlerMessanger) UpdateMessage(ctx *gin.Context) {
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "Message successful updated",
-		"id":      messageUpdate.Id,
-		"body":    messageUpdate.Body,
-		"user_id": messageUpdate.UserId,
+		"message": "Message deleted successfully",
 	})
 }
 
-func (h *HTTPHandlerMessanger) DeleteMessage(ctx *gin.Context) {
+func (h *HTTPHandlerMessanger) RestoreMessage(ctx *gin.Context) {
 	id := ctx.Param("id")
 
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	message, err := h.svcMessanger.RestoreMessage(id, userID)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, message)
+}
+
+func authenticate(ctx *gin.Context) (string, bool) {
 	err := godotenv.Load(".env")
 
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"Error": err.Error(),
 		})
-		return
+		return "", false
 	}
 
 	JWTSecret := os.Getenv("SECRET_JWT")
@@ -157,10 +491,42 @@ func (h *HTTPHandlerMessanger) DeleteMessage(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"Error": "user not authorization",
 		})
+		return "", false
+	}
+
+	return userID, true
+}
+
+func (h *HTTPHandlerMessanger) GetThumbnail(ctx *gin.Context) {
+	id := ctx.Param("id")
+	size := ctx.Param("size")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	thumbnail, content, err := h.svcMessanger.GetThumbnail(userID, id, size)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+	defer content.Close()
+
+	ctx.DataFromReader(http.StatusOK, -1, thumbnail.MimeType, content, map[string]string{
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+func (h *HTTPHandlerMessanger) GetMentions(ctx *gin.Context) {
+	userID, ok := authenticate(ctx)
+	if !ok {
 		return
 	}
 
-	err = h.svcMessanger.DeleteMessage(id, userID)
+	query, err := bindPageQuery(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"Error": err.Error(),
@@ -168,13 +534,612 @@ func (h *HTTPHandlerMessanger) DeleteMessage(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"message": "Message deleted successfully",
-	})
+	mentions, err := h.svcMessanger.GetMentions(userID, query)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, mentions)
+}
+
+func (h *HTTPHandlerMessanger) PinMessage(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	pin, err := h.svcMessanger.PinMessage(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, pin)
+}
+
+func (h *HTTPHandlerMessanger) UnpinMessage(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svcMessanger.UnpinMessage(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Message unpinned successfully",
+	})
+}
+
+func (h *HTTPHandlerMessanger) GetPins(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	pins, err := h.svcMessanger.GetPins(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pins)
+}
+
+func (h *HTTPHandlerMessanger) StarMessage(ctx *gin.Context) {
+	var request starRequest
+
+	id := ctx.Param("id")
+
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"Error": err.Error(),
+			})
+			return
+		}
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	star, err := h.svcMessanger.StarMessage(userID, id, request.Note)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, star)
+}
+
+func (h *HTTPHandlerMessanger) UnstarMessage(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svcMessanger.UnstarMessage(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Message unstarred successfully",
+	})
+}
+
+func (h *HTTPHandlerMessanger) GetStarred(ctx *gin.Context) {
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	query, err := bindPageQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	stars, err := h.svcMessanger.GetStarred(userID, query)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, stars)
+}
+
+func (h *HTTPHandlerMessanger) GetScheduledMessages(ctx *gin.Context) {
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	scheduled, err := h.svcMessanger.GetScheduledMessages(userID, ctx.Query("conversation_id"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+func (h *HTTPHandlerMessanger) UpdateScheduledMessage(ctx *gin.Context) {
+	var request scheduledRequest
+
+	id := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	scheduled, err := h.svcMessanger.UpdateScheduledMessage(userID, id, request.Body, request.SendAt)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+func (h *HTTPHandlerMessanger) CancelScheduledMessage(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svcMessanger.CancelScheduledMessage(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Scheduled message cancelled successfully",
+	})
+}
+
+func (h *HTTPHandlerMessanger) Vote(ctx *gin.Context) {
+	var request voteRequest
+
+	id := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	poll, err := h.svcMessanger.Vote(userID, id, request.OptionIds)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, poll)
+}
+
+func (h *HTTPHandlerMessanger) RetractVote(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	poll, err := h.svcMessanger.RetractVote(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, poll)
+}
+
+func (h *HTTPHandlerMessanger) ClosePoll(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	poll, err := h.svcMessanger.ClosePoll(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, poll)
+}
+
+func (h *HTTPHandlerMessanger) SaveDraft(ctx *gin.Context) {
+	var request draftRequest
+
+	id := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	draft, err := h.svcMessanger.SaveDraft(userID, domain.Draft{
+		ConversationId: id,
+		Body:           request.Body,
+		ParentId:       request.ParentId,
+		DeviceId:       request.DeviceId,
+	})
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, draft)
+}
+
+func (h *HTTPHandlerMessanger) GetDraft(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	draft, err := h.svcMessanger.GetDraft(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, draft)
+}
+
+func (h *HTTPHandlerMessanger) GetDrafts(ctx *gin.Context) {
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	drafts, err := h.svcMessanger.GetDrafts(userID)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, drafts)
+}
+
+func (h *HTTPHandlerMessanger) DeleteDraft(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svcMessanger.DeleteDraft(userID, id, ctx.Query("device_id"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Draft deleted successfully",
+	})
+}
+
+func (h *HTTPHandlerMessanger) MarkDelivered(ctx *gin.Context) {
+	var request deliveredRequest
+
+	id := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svcMessanger.MarkDelivered(userID, id, request.MessageId)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Conversation marked as delivered",
+	})
+}
+
+func (h *HTTPHandlerMessanger) GetReceipts(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	receipts, err := h.svcMessanger.GetReceipts(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, receipts)
+}
+
+func (h *HTTPHandlerMessanger) CreateIncomingWebhook(ctx *gin.Context) {
+	var request incomingWebhookRequest
+
+	id := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	hook, err := h.svcMessanger.CreateIncomingWebhook(userID, id, request.Name)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, hook)
+}
+
+func (h *HTTPHandlerMessanger) GetIncomingWebhooks(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	hooks, err := h.svcMessanger.GetIncomingWebhooks(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, hooks)
+}
+
+func (h *HTTPHandlerMessanger) RotateIncomingWebhook(ctx *gin.Context) {
+	id := ctx.Param("id")
+	hookId := ctx.Param("hook_id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	hook, err := h.svcMessanger.RotateIncomingWebhook(userID, id, hookId)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, hook)
+}
+
+func (h *HTTPHandlerMessanger) DeleteIncomingWebhook(ctx *gin.Context) {
+	id := ctx.Param("id")
+	hookId := ctx.Param("hook_id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svcMessanger.DeleteIncomingWebhook(userID, id, hookId)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Incoming webhook deleted",
+	})
+}
+
+// PostIncoming is authenticated by the webhook token in the path instead of a JWT.
+func (h *HTTPHandlerMessanger) PostIncoming(ctx *gin.Context) {
+	var request incomingMessageRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	if request.Body == "" {
+		request.Body = request.Text
+	}
+
+	message, err := h.svcMessanger.PostIncoming(ctx.Param("token"), domain.Message{
+		Body:     request.Body,
+		ParentId: request.ParentId,
+	})
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, message)
+}
+
+func bindMultipartMessage(ctx *gin.Context, message *domain.Message) (*multipart.Form, error) {
+	_ = godotenv.Load(".env")
+
+	maxSize, err := strconv.ParseInt(os.Getenv("ATTACHMENT_MAX_SIZE"), 10, 64)
+	if err != nil || maxSize <= 0 {
+		maxSize = defaultAttachmentMaxSize
+	}
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize)
+
+	if err := ctx.Request.ParseMultipartForm(32 << 20); err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid upload: %v", err))
+	}
+
+	form := ctx.Request.MultipartForm
+	message.ConversationId = ctx.Request.FormValue("conversation_id")
+	message.Type = ctx.Request.FormValue("type")
+	message.Body = ctx.Request.FormValue("body")
+	message.ParentId = ctx.Request.FormValue("parent_id")
+	message.QuoteId = ctx.Request.FormValue("quote_id")
+
+	if sendAt := ctx.Request.FormValue("send_at"); sendAt != "" {
+		value, err := time.Parse(time.RFC3339, sendAt)
+		if err != nil {
+			return nil, errors.New("send_at must be an RFC3339 time")
+		}
+		message.SendAt = &value
+	}
+	return form, nil
+}
+
+func bindPageQuery(ctx *gin.Context) (domain.PageQuery, error) {
+	query := domain.PageQuery{
+		Before: ctx.Query("before"),
+		After:  ctx.Query("after"),
+	}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			return query, errors.New("limit must be a number")
+		}
+		query.Limit = value
+	}
+	return query, nil
+}
+
+func bindTime(ctx *gin.Context, key string) (time.Time, error) {
+	value := ctx.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New(fmt.Sprintf("%s must be an RFC3339 timestamp", key))
+	}
+	return t, nil
+}
+
+func errorStatus(err error) int {
+	if errors.Is(err, services.ErrNotConversationMember) || errors.Is(err, services.ErrNotAdmin) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, services.ErrRateLimited) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusBadRequest
 }
 
 func ValidateToken(authHeader string, jwtSecret string) (string, error) {
-	if authHeader == "" {
+	if len(authHeader) < 7 {
 		return "", errors.New("token not found")
 	}
 
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"messenger/internal/core/services"
+)
+
+type HTTPHandlerPresence struct {
+	svc services.PresenceService
+}
+
+type presenceRequest struct {
+	Status    string `json:"status" binding:"required"`
+	SessionId string `json:"session_id" binding:"required"`
+}
+
+func NewHTTPHandlerPresence(PresenceService services.PresenceService) *HTTPHandlerPresence {
+	return &HTTPHandlerPresence{
+		svc: PresenceService,
+	}
+}
+
+func (h *HTTPHandlerPresence) SetPresence(ctx *gin.Context) {
+	var request presenceRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svc.SetStatus(userID, request.SessionId, request.Status)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Presence updated",
+	})
+}
+
+func (h *HTTPHandlerPresence) GetPresences(ctx *gin.Context) {
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	var ids []string
+	for _, id := range strings.Split(ctx.Query("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	presences, err := h.svc.GetPresences(userID, ids)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, presences)
+}
+
+func (h *HTTPHandlerPresence) StartTyping(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svc.StartTyping(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Typing started",
+	})
+}
+
+func (h *HTTPHandlerPresence) StopTyping(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svc.StopTyping(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Typing stopped",
+	})
+}
+
+func (h *HTTPHandlerPresence) GetTyping(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	typing, err := h.svc.GetTyping(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, typing)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"messenger/internal/core/services"
+)
+
+type HTTPHandlerRead struct {
+	svc services.ReadService
+}
+
+type readRequest struct {
+	MessageId string `json:"message_id"`
+}
+
+func NewHTTPHandlerRead(ReadService services.ReadService) *HTTPHandlerRead {
+	return &HTTPHandlerRead{
+		svc: ReadService,
+	}
+}
+
+func (h *HTTPHandlerRead) MarkRead(ctx *gin.Context) {
+	var request readRequest
+
+	id := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svc.MarkRead(userID, id, request.MessageId)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Conversation marked as read",
+	})
+}
+
+func (h *HTTPHandlerRead) GetReadMarkers(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	markers, err := h.svc.GetReadMarkers(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, markers)
+}
+
+func (h *HTTPHandlerRead) GetUnreadCounts(ctx *gin.Context) {
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	counts, err := h.svc.GetUnreadCounts(userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, counts)
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -55,7 +54,7 @@ func (h *HTTPHandlerUser) GetOneUser(ctx *gin.Context) {
 }
 
 func (h *HTTPHandlerUser) GetAllUsers(ctx *gin.Context) {
-	users, err := h.svc.GetAllUsers()
+	query, err := bindPageQuery(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"Error": err.Error(),
@@ -63,22 +62,37 @@ func (h *HTTPHandlerUser) GetAllUsers(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, users)
-}
-
-func (h *HTTPHandlerUser) GetAllUsersByExportData(ctx *gin.Context) {
-	users, err := h.svc.GetAllUsers()
+	users, err := h.svc.GetAllUsers(query)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
+		ctx.JSON(http.StatusBadRequest, gin.H{
 			"Error": err.Error(),
 		})
 		return
 	}
 
-	fmt.Println("users ::", users)
+	ctx.JSON(http.StatusOK, users)
+}
+
+func (h *HTTPHandlerUser) GetAllUsersByExportData(ctx *gin.Context) {
+	var users []*domain.User
+	query := domain.PageQuery{Limit: domain.MaxPageLimit}
+	for {
+		page, err := h.svc.GetAllUsers(query)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"Error": err.Error(),
+			})
+			return
+		}
+
+		users = append(users, page.Users...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Before = page.NextCursor
+	}
 
 	file := excelize.NewFile()
-	fmt.Println("file ::", file)
 
 	sheetName := "Sheet1"
 	sheetIndex, err := file.NewSheet(sheetName)
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"messenger/internal/core/domain"
+	"messenger/internal/core/services"
+)
+
+type HTTPHandlerWebhook struct {
+	svc services.WebhookService
+}
+
+type webhookRequest struct {
+	Url         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	Description string   `json:"description"`
+}
+
+func NewHTTPHandlerWebhook(WebhookService services.WebhookService) *HTTPHandlerWebhook {
+	return &HTTPHandlerWebhook{
+		svc: WebhookService,
+	}
+}
+
+func (h *HTTPHandlerWebhook) CreateWebhook(ctx *gin.Context) {
+	var request webhookRequest
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.svc.CreateWebhook(userID, domain.Webhook{
+		Url:         request.Url,
+		Events:      request.Events,
+		Description: request.Description,
+	})
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, webhook)
+}
+
+func (h *HTTPHandlerWebhook) GetWebhooks(ctx *gin.Context) {
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	webhooks, err := h.svc.GetWebhooks(userID)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhooks)
+}
+
+func (h *HTTPHandlerWebhook) DeleteWebhook(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svc.DeleteWebhook(userID, id)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Webhook deleted",
+	})
+}
+
+func (h *HTTPHandlerWebhook) GetDeliveries(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.svc.GetDeliveries(userID, id, ctx.Query("status"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+func (h *HTTPHandlerWebhook) RetryDelivery(ctx *gin.Context) {
+	id := ctx.Param("id")
+	deliveryId := ctx.Param("delivery_id")
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.svc.RetryDelivery(userID, id, deliveryId)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Webhook delivery queued for retry",
+	})
+}
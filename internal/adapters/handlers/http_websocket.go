@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"messenger/internal/adapters/realtime"
+	"messenger/internal/core/domain"
+	"messenger/internal/core/services"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10
+
+	// browsers cannot set headers on a websocket handshake, so they send the
+	// token as the second of the subprotocols "bearer, <token>"
+	authProtocol = "bearer"
+)
+
+type HTTPHandlerWebSocket struct {
+	hub      *realtime.Hub
+	presence *services.PresenceService
+	upgrader websocket.Upgrader
+}
+
+// clientMessage is what a client may send over the socket: a presence status
+// ("presence") or a typing signal ("typing.start", "typing.stop").
+type clientMessage struct {
+	Type           string `json:"type"`
+	Status         string `json:"status"`
+	ConversationId string `json:"conversation_id"`
+}
+
+func NewHTTPHandlerWebSocket(hub *realtime.Hub, presence *services.PresenceService, allowedOrigins []string) *HTTPHandlerWebSocket {
+	return &HTTPHandlerWebSocket{
+		hub:      hub,
+		presence: presence,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			Subprotocols:    []string{authProtocol},
+			CheckOrigin: func(r *http.Request) bool {
+				return checkOrigin(r, allowedOrigins)
+			},
+		},
+	}
+}
+
+func (h *HTTPHandlerWebSocket) Connect(ctx *gin.Context) {
+	protocols := websocket.Subprotocols(ctx.Request)
+	if ctx.Request.Header.Get("Authorization") == "" && len(protocols) == 2 && protocols[0] == authProtocol {
+		ctx.Request.Header.Set("Authorization", "Bearer "+protocols[1])
+	}
+
+	userID, ok := authenticate(ctx)
+	if !ok {
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		return
+	}
+
+	sessionID := ctx.Query("session_id")
+	if sessionID == "" {
+		sessionID = uuid.New().String()
+	}
+
+	client, missed := h.hub.Subscribe(userID, ctx.Query("last_event_id"))
+	if err := h.presence.Heartbeat(userID, sessionID); err != nil {
+		log.Printf("presence of %s: %v", userID, err)
+	}
+
+	go h.writePump(conn, client, missed)
+	h.readPump(conn, client, sessionID)
+}
+
+func (h *HTTPHandlerWebSocket) readPump(conn *websocket.Conn, client *realtime.Client, sessionID string) {
+	defer func() {
+		h.hub.Unregister(client)
+		conn.Close()
+		if err := h.presence.Disconnect(client.UserId, sessionID); err != nil {
+			log.Printf("presence of %s: %v", client.UserId, err)
+		}
+	}()
+
+	conn.SetReadLimit(512)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		if err := h.presence.Heartbeat(client.UserId, sessionID); err != nil {
+			log.Printf("presence of %s: %v", client.UserId, err)
+		}
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var message clientMessage
+		if json.Unmarshal(data, &message) != nil {
+			continue
+		}
+
+		switch message.Type {
+		case "presence":
+			err = h.presence.SetStatus(client.UserId, sessionID, message.Status)
+		case "typing.start":
+			err = h.presence.StartTyping(client.UserId, message.ConversationId)
+		case "typing.stop":
+			err = h.presence.StopTyping(client.UserId, message.ConversationId)
+		}
+		if err != nil {
+			log.Printf("websocket message from %s: %v", client.UserId, err)
+		}
+	}
+}
+
+func (h *HTTPHandlerWebSocket) writePump(conn *websocket.Conn, client *realtime.Client, missed []domain.Event) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for _, event := range missed {
+		_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := conn.WriteJSON(event); err != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case event, ok := <-client.Send:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				_ = conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// checkOrigin accepts clients without an Origin header, which are not browsers,
+// pages served from this host, and the configured origins.
+func checkOrigin(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if parsed, err := url.Parse(origin); err == nil && strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	return slices.Contains(allowedOrigins, origin)
+}
@@ -0,0 +1,129 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"messenger/internal/core/domain"
+)
+
+const DefaultMaxPixels = 40_000_000
+
+// ImageThumbnailer scales JPEG, PNG and GIF images with an area-averaging filter
+// using only the standard library.
+type ImageThumbnailer struct {
+	maxPixels int
+}
+
+func NewImageThumbnailer(maxPixels int) *ImageThumbnailer {
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+
+	return &ImageThumbnailer{
+		maxPixels: maxPixels,
+	}
+}
+
+func (t *ImageThumbnailer) Thumbnails(src io.Reader, sizes []domain.ThumbnailSize) ([]*domain.Thumbnail, error) {
+	var buf bytes.Buffer
+	config, format, err := image.DecodeConfig(io.TeeReader(src, &buf))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("image not readable: %v", err))
+	}
+
+	// refuse decompression bombs before allocating the full bitmap
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > t.maxPixels {
+		return nil, errors.New("image dimensions are too large")
+	}
+
+	img, _, err := image.Decode(io.MultiReader(&buf, src))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("image not readable: %v", err))
+	}
+
+	thumbnails := make([]*domain.Thumbnail, 0, len(sizes))
+	for _, size := range sizes {
+		scaled := scale(img, size.MaxSide)
+
+		var out bytes.Buffer
+		mimeType := "image/png"
+		if format == "jpeg" {
+			mimeType = "image/jpeg"
+			err = jpeg.Encode(&out, scaled, &jpeg.Options{Quality: 80})
+		} else {
+			err = png.Encode(&out, scaled)
+		}
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("thumbnail not encoded: %v", err))
+		}
+
+		thumbnails = append(thumbnails, &domain.Thumbnail{
+			Size:     size.Name,
+			Width:    scaled.Bounds().Dx(),
+			Height:   scaled.Bounds().Dy(),
+			MimeType: mimeType,
+			Content:  out.Bytes(),
+		})
+	}
+	return thumbnails, nil
+}
+
+func scale(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if maxSide <= 0 || (width <= maxSide && height <= maxSide) {
+		dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+		return dst
+	}
+
+	dstWidth, dstHeight := maxSide, maxSide
+	if width > height {
+		dstHeight = max(1, height*maxSide/width)
+	} else {
+		dstWidth = max(1, width*maxSide/height)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/dstHeight)
+
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/dstWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+
+			// average premultiplied channels, then convert back to straight alpha
+			c := color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			}
+			dst.Set(x, y, color.NRGBAModel.Convert(c))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,70 @@
+// Package netguard keeps requests that the server makes to user-supplied URLs,
+// such as link previews and webhooks, away from internal services.
+package netguard
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+var ErrBlockedAddress = errors.New("address is not allowed")
+
+// blockedPrefixes lists the ranges that are not reachable on the public internet,
+// on top of the loopback, private and link-local checks of net/netip.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// Control is meant for net.Dialer. It runs after DNS resolution, so a host that
+// resolves to a private address is refused even behind redirects or rebinding.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !PublicAddr(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// CheckHost refuses literal private addresses and localhost names up front;
+// other names are checked by Control when they are dialed.
+func CheckHost(host string) error {
+	if ip, err := netip.ParseAddr(host); err == nil && !PublicAddr(ip) {
+		return ErrBlockedAddress
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package netguard
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.0.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"198.18.0.1", false},
+		{"::", false},
+		{"::1", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::7f00:1", false},
+		{"2002:7f00:1::", false},
+	}
+
+	for _, test := range tests {
+		if got := PublicAddr(netip.MustParseAddr(test.addr)); got != test.public {
+			t.Errorf("PublicAddr(%s) = %v, want %v", test.addr, got, test.public)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package presence
+
+import (
+	"sync"
+	"time"
+
+	"messenger/internal/core/domain"
+)
+
+// MemoryPresenceStore keeps presence and typing state in process memory. It suits a
+// single instance; several instances need a shared store such as Redis behind the
+// same port.
+type MemoryPresenceStore struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]domain.PresenceSession
+	typing   map[string]map[string]domain.Typing
+}
+
+func NewMemoryPresenceStore() *MemoryPresenceStore {
+	return &MemoryPresenceStore{
+		sessions: make(map[string]map[string]domain.PresenceSession),
+		typing:   make(map[string]map[string]domain.Typing),
+	}
+}
+
+func (s *MemoryPresenceStore) SetSession(session domain.PresenceSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sessions[session.UserId] == nil {
+		s.sessions[session.UserId] = make(map[string]domain.PresenceSession)
+	}
+	s.sessions[session.UserId][session.SessionId] = session
+	return nil
+}
+
+func (s *MemoryPresenceStore) RemoveSession(userId, sessionId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions[userId], sessionId)
+	if len(s.sessions[userId]) == 0 {
+		delete(s.sessions, userId)
+	}
+	return nil
+}
+
+func (s *MemoryPresenceStore) GetSessions(userIds []string, now time.Time) ([]*domain.PresenceSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions []*domain.PresenceSession
+	for _, userId := range userIds {
+		for _, session := range s.sessions[userId] {
+			if session.ExpiresAt.After(now) {
+				session := session
+				sessions = append(sessions, &session)
+			}
+		}
+	}
+	return sessions, nil
+}
+
+func (s *MemoryPresenceStore) ExpireSessions(now time.Time) ([]*domain.PresenceSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*domain.PresenceSession
+	for userId, sessions := range s.sessions {
+		for sessionId, session := range sessions {
+			if !session.ExpiresAt.After(now) {
+				session := session
+				expired = append(expired, &session)
+				delete(sessions, sessionId)
+			}
+		}
+		if len(sessions) == 0 {
+			delete(s.sessions, userId)
+		}
+	}
+	return expired, nil
+}
+
+func (s *MemoryPresenceStore) SetTyping(typing domain.Typing) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.typing[typing.ConversationId] == nil {
+		s.typing[typing.ConversationId] = make(map[string]domain.Typing)
+	}
+
+	current, ok := s.typing[typing.ConversationId][typing.UserId]
+	started := !ok || !current.ExpiresAt.After(time.Now().UTC())
+	s.typing[typing.ConversationId][typing.UserId] = typing
+	return started, nil
+}
+
+func (s *MemoryPresenceStore) RemoveTyping(conversationId, userId string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.typing[conversationId][userId]
+	delete(s.typing[conversationId], userId)
+	if len(s.typing[conversationId]) == 0 {
+		delete(s.typing, conversationId)
+	}
+	return ok, nil
+}
+
+func (s *MemoryPresenceStore) GetTyping(conversationId string, now time.Time) ([]*domain.Typing, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var typing []*domain.Typing
+	for _, entry := range s.typing[conversationId] {
+		if entry.ExpiresAt.After(now) {
+			entry := entry
+			typing = append(typing, &entry)
+		}
+	}
+	return typing, nil
+}
+
+func (s *MemoryPresenceStore) ExpireTyping(now time.Time) ([]*domain.Typing, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*domain.Typing
+	for conversationId, entries := range s.typing {
+		for userId, entry := range entries {
+			if !entry.ExpiresAt.After(now) {
+				entry := entry
+				expired = append(expired, &entry)
+				delete(entries, userId)
+			}
+		}
+		if len(entries) == 0 {
+			delete(s.typing, conversationId)
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,182 @@
+package realtime
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"messenger/internal/core/domain"
+)
+
+const (
+	clientBuffer = 64
+	historySize  = 1000
+)
+
+type Client struct {
+	UserId string
+	Send   chan domain.Event
+}
+
+// Hub fans events out to connected clients and keeps the latest ones for replay.
+// Event ids are "<epoch>-<seq>"; the epoch changes on every start, so an id from
+// before a restart is recognised instead of being mistaken for a recent one.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[string]map[*Client]struct{}
+	history []domain.Event
+	epoch   string
+	seq     uint64
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		clients: make(map[string]map[*Client]struct{}),
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+}
+
+// Subscribe registers a client and returns the events it missed since lastEventId.
+// Both happen under the same lock so nothing published in between is lost or sent twice.
+// When the missed events are no longer all known, the client gets a single resync
+// event instead, and should reload its state.
+func (h *Hub) Subscribe(userId, lastEventId string) (*Client, []domain.Event) {
+	client := &Client{
+		UserId: userId,
+		Send:   make(chan domain.Event, clientBuffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var missed []domain.Event
+	if lastEventId != "" {
+		last, ok := h.parseId(lastEventId)
+		switch {
+		case !ok || last > h.seq:
+			missed = []domain.Event{h.resync(userId, "unknown event id")}
+		case last+1 < h.oldest():
+			missed = []domain.Event{h.resync(userId, "events were missed")}
+		default:
+			for _, event := range h.history {
+				id, _ := h.parseId(event.Id)
+				if id > last && hasUser(event, userId) {
+					missed = append(missed, event)
+				}
+			}
+		}
+	}
+
+	if h.clients[userId] == nil {
+		h.clients[userId] = make(map[*Client]struct{})
+	}
+	h.clients[userId][client] = struct{}{}
+	return client, missed
+}
+
+func (h *Hub) Unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(client)
+}
+
+// Publish sends an event to the clients of its users. Ephemeral events take no
+// sequence number and skip the history, so they never push out replayable ones,
+// and a client too slow for one simply misses it.
+func (h *Hub) Publish(event domain.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if event.Ephemeral {
+		event.Id = ""
+		for _, userId := range event.UserIds {
+			for client := range h.clients[userId] {
+				select {
+				case client.Send <- event:
+				default:
+				}
+			}
+		}
+		return
+	}
+
+	h.seq++
+	event.Id = h.formatId(h.seq)
+
+	h.history = append(h.history, event)
+	if len(h.history) > historySize {
+		h.history = h.history[len(h.history)-historySize:]
+	}
+
+	for _, userId := range event.UserIds {
+		for client := range h.clients[userId] {
+			select {
+			case client.Send <- event:
+			default:
+				// a slow client is disconnected rather than silently losing the
+				// event; it reconnects with its last id and gets the replay
+				h.remove(client)
+			}
+		}
+	}
+}
+
+// remove must be called with the lock held. Closing Send ends the connection.
+func (h *Hub) remove(client *Client) {
+	clients, ok := h.clients[client.UserId]
+	if !ok {
+		return
+	}
+	if _, ok := clients[client]; !ok {
+		return
+	}
+
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(h.clients, client.UserId)
+	}
+	close(client.Send)
+}
+
+func (h *Hub) resync(userId, reason string) domain.Event {
+	return domain.Event{
+		Id:        h.formatId(h.seq),
+		Type:      domain.EventResync,
+		Data:      map[string]string{"reason": reason},
+		UserIds:   []string{userId},
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// oldest is the sequence of the oldest event still kept for replay.
+func (h *Hub) oldest() uint64 {
+	if len(h.history) == 0 {
+		return h.seq + 1
+	}
+	id, _ := h.parseId(h.history[0].Id)
+	return id
+}
+
+func (h *Hub) formatId(seq uint64) string {
+	return h.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+func (h *Hub) parseId(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != h.epoch {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(seq, 10, 64)
+	return value, err == nil
+}
+
+func hasUser(event domain.Event, userId string) bool {
+	for _, id := range event.UserIds {
+		if id == userId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package realtime
+
+import (
+	"testing"
+
+	"messenger/internal/core/domain"
+)
+
+func TestEphemeralEventsSkipHistory(t *testing.T) {
+	hub := NewHub()
+	client, _ := hub.Subscribe("u1", "")
+
+	hub.Publish(domain.Event{Type: domain.EventMessageCreated, UserIds: []string{"u1"}})
+	first := <-client.Send
+
+	for i := 0; i < historySize+10; i++ {
+		hub.Publish(domain.Event{Type: domain.EventTypingStarted, UserIds: []string{"u1"}, Ephemeral: true})
+		if event := <-client.Send; event.Id != "" {
+			t.Fatalf("ephemeral event got id %q", event.Id)
+		}
+	}
+
+	hub.Publish(domain.Event{Type: domain.EventMessageUpdated, UserIds: []string{"u1"}})
+	second := <-client.Send
+	if want := hub.formatId(2); second.Id != want {
+		t.Errorf("event after ephemeral ones has id %s, want %s", second.Id, want)
+	}
+
+	// the ephemeral burst must not have pushed the first event out of the replay
+	_, missed := hub.Subscribe("u1", first.Id)
+	if len(missed) != 1 || missed[0].Id != second.Id {
+		t.Errorf("replay after %s = %+v, want only %s", first.Id, missed, second.Id)
+	}
+}
+
+func TestEphemeralEventsDoNotDisconnectSlowClients(t *testing.T) {
+	hub := NewHub()
+	client, _ := hub.Subscribe("u1", "")
+
+	for i := 0; i < clientBuffer+1; i++ {
+		hub.Publish(domain.Event{Type: domain.EventTypingStarted, UserIds: []string{"u1"}, Ephemeral: true})
+	}
+
+	hub.mu.RLock()
+	_, connected := hub.clients["u1"][client]
+	hub.mu.RUnlock()
+	if !connected {
+		t.Error("a full buffer of ephemeral events disconnected the client")
+	}
+}
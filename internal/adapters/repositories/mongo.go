@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+const (
+	MongoUrl       = "mongodb://0.0.0.0:27017"
+	MongoDatabase  = "management_messenger"
+	MongodbTimeout = "20"
+)
+
+var (
+	mongoMu     sync.Mutex
+	mongoClient *mongo.Client
+)
+
+// newMongoCollection connects once and hands every repository the same client.
+func newMongoCollection(name string) (*mongo.Client, *mongo.Collection) {
+	mongoMu.Lock()
+	defer mongoMu.Unlock()
+
+	if mongoClient != nil {
+		return mongoClient, mongoClient.Database(MongoDatabase).Collection(name)
+	}
+
+	//err := godotenv.Load(".env")
+	//
+	//if err != nil {
+	//	log.Fatal("Error loading file .env")
+	//}
+
+	timeout, err := strconv.Atoi(MongodbTimeout)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(MongoUrl))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = client.Ping(ctx, readpref.Primary())
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mongoClient = client
+	return client, client.Database(MongoDatabase).Collection(name)
+}
@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type AttachmentMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+	thumbnails *mongo.Collection
+}
+
+func NewAttachmentMongoRepository() *AttachmentMongoRepository {
+	client, collection := newMongoCollection("attachments")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "message_id", Value: 1}},
+	})
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "preview_status", Value: 1}},
+	})
+
+	thumbnails := client.Database(MongoDatabase).Collection("thumbnails")
+	_, _ = thumbnails.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "attachment_id", Value: 1}, {Key: "size", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return &AttachmentMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+		thumbnails: thumbnails,
+	}
+}
+
+func (a *AttachmentMongoRepository) AddAttachments(attachments []*domain.Attachment) error {
+	documents := make([]interface{}, 0, len(attachments))
+	for _, attachment := range attachments {
+		documents = append(documents, attachment)
+	}
+
+	if len(documents) == 0 {
+		return nil
+	}
+
+	_, err := a.collection.InsertMany(context.Background(), documents)
+	if err != nil {
+		return errors.New(fmt.Sprintf("attachment not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (a *AttachmentMongoRepository) GetAttachment(id string) (*domain.Attachment, error) {
+	attachment := &domain.Attachment{}
+	err := a.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&attachment)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("attachment not found: %v", err.Error()))
+	}
+	return attachment, nil
+}
+
+func (a *AttachmentMongoRepository) GetAttachments(messageIds []string) ([]*domain.Attachment, error) {
+	var attachments []*domain.Attachment
+	if len(messageIds) == 0 {
+		return attachments, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	req, err := a.collection.Find(context.Background(), bson.M{"message_id": bson.M{"$in": messageIds}}, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("attachments not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var attachment *domain.Attachment
+		if err := req.Decode(&attachment); err != nil {
+			return nil, errors.New(fmt.Sprintf("attachments not found: %v", err.Error()))
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func (a *AttachmentMongoRepository) DeleteAttachments(messageIds []string) error {
+	_, err := a.collection.DeleteMany(context.Background(), bson.M{"message_id": bson.M{"$in": messageIds}})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete attachments: %v", err.Error()))
+	}
+	return nil
+}
+
+// ClaimPendingPreviews leases pending previews one at a time with
+// findOneAndUpdate, so two instances never render the same thumbnails.
+func (a *AttachmentMongoRepository) ClaimPendingPreviews(now time.Time, owner string, lease time.Duration, limit int) ([]*domain.Attachment, error) {
+	filter := bson.M{
+		"preview_status": domain.PreviewPending,
+		"$or": bson.A{
+			bson.M{"claimed_until": nil},
+			bson.M{"claimed_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"claimed_by":    owner,
+		"claimed_until": now.Add(lease),
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	attachments := []*domain.Attachment{}
+	for len(attachments) < limit {
+		attachment := &domain.Attachment{}
+		err := a.collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&attachment)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return attachments, errors.New(fmt.Sprintf("pending previews not claimed: %v", err.Error()))
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func (a *AttachmentMongoRepository) SetPreviewStatus(id, status string) error {
+	req, err := a.collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"preview_status": status}})
+	if err != nil {
+		return errors.New(fmt.Sprintf("attachment not updated: %v", err.Error()))
+	}
+	if req.MatchedCount == 0 {
+		return errors.New("attachment not found")
+	}
+	return nil
+}
+
+func (a *AttachmentMongoRepository) AddThumbnails(thumbnails []*domain.Thumbnail) error {
+	for _, thumbnail := range thumbnails {
+		filter := bson.M{"attachment_id": thumbnail.AttachmentId, "size": thumbnail.Size}
+		_, err := a.thumbnails.ReplaceOne(context.Background(), filter, thumbnail, options.Replace().SetUpsert(true))
+		if err != nil {
+			return errors.New(fmt.Sprintf("thumbnail not saved: %v", err.Error()))
+		}
+	}
+	return nil
+}
+
+func (a *AttachmentMongoRepository) GetThumbnails(attachmentIds []string) ([]*domain.Thumbnail, error) {
+	var thumbnails []*domain.Thumbnail
+	if len(attachmentIds) == 0 {
+		return thumbnails, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "width", Value: 1}})
+	req, err := a.thumbnails.Find(context.Background(), bson.M{"attachment_id": bson.M{"$in": attachmentIds}}, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("thumbnails not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var thumbnail *domain.Thumbnail
+		if err := req.Decode(&thumbnail); err != nil {
+			return nil, errors.New(fmt.Sprintf("thumbnails not found: %v", err.Error()))
+		}
+		thumbnails = append(thumbnails, thumbnail)
+	}
+	return thumbnails, nil
+}
+
+func (a *AttachmentMongoRepository) DeleteThumbnails(attachmentIds []string) error {
+	_, err := a.thumbnails.DeleteMany(context.Background(), bson.M{"attachment_id": bson.M{"$in": attachmentIds}})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete thumbnails: %v", err.Error()))
+	}
+	return nil
+}
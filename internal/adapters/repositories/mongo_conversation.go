@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type ConversationMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+	members    *mongo.Collection
+}
+
+func NewConversationMongoRepository() *ConversationMongoRepository {
+	client, collection := newMongoCollection("conversations")
+	members := client.Database(MongoDatabase).Collection("conversation_members")
+
+	// group conversations have an empty key, so only direct ones are unique
+	_, _ = collection.Indexes().DropOne(context.Background(), "direct_key_1")
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "direct_key", Value: 1}},
+		Options: options.Index().
+			SetName("direct_key_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"type": domain.ConversationDirect}),
+	})
+	_, _ = members.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "conversation_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return &ConversationMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+		members:    members,
+	}
+}
+
+func (c *ConversationMongoRepository) CreateConversation(conversation domain.Conversation, memberIds []string) error {
+	_, err := c.collection.InsertOne(context.Background(), conversation)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrDirectConversationExists
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("conversation not saved: %v", err.Error()))
+	}
+
+	members := make([]interface{}, 0, len(memberIds))
+	for _, userId := range memberIds {
+		members = append(members, domain.ConversationMember{
+			ConversationId: conversation.Id,
+			UserId:         userId,
+			JoinedAt:       time.Now().UTC(),
+		})
+	}
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	_, err = c.members.InsertMany(context.Background(), members)
+	if err != nil {
+		_, _ = c.collection.DeleteOne(context.Background(), bson.M{"_id": conversation.Id})
+		return errors.New(fmt.Sprintf("conversation member not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (c *ConversationMongoRepository) GetOneConversation(id string) (*domain.Conversation, error) {
+	conversation := &domain.Conversation{}
+	err := c.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&conversation)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("conversation not found: %v", err.Error()))
+	}
+	return conversation, nil
+}
+
+func (c *ConversationMongoRepository) GetDirectConversation(directKey string) (*domain.Conversation, error) {
+	conversation := &domain.Conversation{}
+	filter := bson.M{"type": domain.ConversationDirect, "direct_key": directKey}
+	err := c.collection.FindOne(context.Background(), filter).Decode(&conversation)
+	if err == mongo.ErrNoDocuments {
+		return nil, domain.ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("conversation not found: %v", err.Error()))
+	}
+	return conversation, nil
+}
+
+func (c *ConversationMongoRepository) GetUserConversations(userId string) ([]*domain.Conversation, error) {
+	members, err := c.members.Find(context.Background(), bson.M{"user_id": userId})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("conversations not found: %v", err.Error()))
+	}
+
+	var ids []string
+	defer members.Close(context.Background())
+	for members.Next(context.Background()) {
+		var member domain.ConversationMember
+		if err := members.Decode(&member); err != nil {
+			return nil, errors.New(fmt.Sprintf("conversations not found: %v", err.Error()))
+		}
+		ids = append(ids, member.ConversationId)
+	}
+
+	var conversations []*domain.Conversation
+	if len(ids) == 0 {
+		return conversations, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	req, err := c.collection.Find(context.Background(), bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("conversations not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var conversation *domain.Conversation
+		if err := req.Decode(&conversation); err != nil {
+			return nil, errors.New(fmt.Sprintf("conversations not found: %v", err.Error()))
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+func (c *ConversationMongoRepository) AddMember(member domain.ConversationMember) error {
+	_, err := c.members.InsertOne(context.Background(), member)
+	if err != nil {
+		return errors.New(fmt.Sprintf("conversation member not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (c *ConversationMongoRepository) RemoveMember(conversationId, userId string) error {
+	result, err := c.members.DeleteOne(context.Background(), bson.M{"conversation_id": conversationId, "user_id": userId})
+	if err != nil {
+		return errors.New("unable to remove conversation member :(")
+	}
+
+	if result.DeletedCount < 1 {
+		return errors.New("conversation member not found")
+	}
+	return nil
+}
+
+func (c *ConversationMongoRepository) GetMembers(conversationId string) ([]*domain.ConversationMember, error) {
+	var members []*domain.ConversationMember
+	opts := options.Find().SetSort(bson.D{{Key: "joined_at", Value: 1}})
+	req, err := c.members.Find(context.Background(), bson.M{"conversation_id": conversationId}, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("conversation members not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var member *domain.ConversationMember
+		if err := req.Decode(&member); err != nil {
+			return nil, errors.New(fmt.Sprintf("conversation members not found: %v", err.Error()))
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// GetContactIds returns the distinct members of every conversation the user is in,
+// the user included.
+func (c *ConversationMongoRepository) GetContactIds(userId string) ([]string, error) {
+	conversationIds, err := c.members.Distinct(context.Background(), "conversation_id", bson.M{"user_id": userId})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("contacts not found: %v", err.Error()))
+	}
+	if len(conversationIds) == 0 {
+		return []string{}, nil
+	}
+
+	userIds, err := c.members.Distinct(context.Background(), "user_id", bson.M{"conversation_id": bson.M{"$in": conversationIds}})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("contacts not found: %v", err.Error()))
+	}
+
+	ids := make([]string, 0, len(userIds))
+	for _, id := range userIds {
+		if userId, ok := id.(string); ok {
+			ids = append(ids, userId)
+		}
+	}
+	return ids, nil
+}
+
+func (c *ConversationMongoRepository) IsMember(conversationId, userId string) (bool, error) {
+	count, err := c.members.CountDocuments(context.Background(), bson.M{"conversation_id": conversationId, "user_id": userId})
+	if err != nil {
+		return false, errors.New("error occured while checking for the conversation member")
+	}
+	return count > 0, nil
+}
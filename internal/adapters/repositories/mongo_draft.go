@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type DraftMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+}
+
+func NewDraftMongoRepository() *DraftMongoRepository {
+	client, collection := newMongoCollection("drafts")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "conversation_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return &DraftMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+	}
+}
+
+func (d *DraftMongoRepository) SetDraft(draft domain.Draft) error {
+	filter := bson.M{"user_id": draft.UserId, "conversation_id": draft.ConversationId}
+	_, err := d.collection.ReplaceOne(context.Background(), filter, draft, options.Replace().SetUpsert(true))
+	if err != nil {
+		return errors.New(fmt.Sprintf("draft not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (d *DraftMongoRepository) GetDraft(userId, conversationId string) (*domain.Draft, error) {
+	var draft *domain.Draft
+	filter := bson.M{"user_id": userId, "conversation_id": conversationId}
+	if err := d.collection.FindOne(context.Background(), filter).Decode(&draft); err != nil {
+		return nil, errors.New("draft not found")
+	}
+	return draft, nil
+}
+
+func (d *DraftMongoRepository) GetDrafts(userId string) ([]*domain.Draft, error) {
+	drafts := []*domain.Draft{}
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	req, err := d.collection.Find(context.Background(), bson.M{"user_id": userId}, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("drafts not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var draft *domain.Draft
+		if err := req.Decode(&draft); err != nil {
+			return nil, errors.New(fmt.Sprintf("drafts not found: %v", err.Error()))
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, nil
+}
+
+func (d *DraftMongoRepository) DeleteDraft(userId, conversationId string) (bool, error) {
+	req, err := d.collection.DeleteOne(context.Background(), bson.M{"user_id": userId, "conversation_id": conversationId})
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("unable to delete draft: %v", err.Error()))
+	}
+	return req.DeletedCount > 0, nil
+}
+
+// ClearDraft deletes the draft only while it is for the same thread as parentId.
+func (d *DraftMongoRepository) ClearDraft(userId, conversationId, parentId string) (bool, error) {
+	req, err := d.collection.DeleteOne(context.Background(), bson.M{"user_id": userId, "conversation_id": conversationId, "parent_id": parentId})
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("unable to delete draft: %v", err.Error()))
+	}
+	return req.DeletedCount > 0, nil
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type IncomingWebhookMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+}
+
+func NewIncomingWebhookMongoRepository() *IncomingWebhookMongoRepository {
+	client, collection := newMongoCollection("incoming_webhooks")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "token_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "conversation_id", Value: 1}},
+	})
+
+	return &IncomingWebhookMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+	}
+}
+
+func (i *IncomingWebhookMongoRepository) AddIncomingWebhook(hook domain.IncomingWebhook) error {
+	_, err := i.collection.InsertOne(context.Background(), hook)
+	if err != nil {
+		return errors.New(fmt.Sprintf("incoming webhook not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (i *IncomingWebhookMongoRepository) GetIncomingWebhook(id string) (*domain.IncomingWebhook, error) {
+	hook := &domain.IncomingWebhook{}
+	err := i.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&hook)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("incoming webhook not found: %v", err.Error()))
+	}
+	return hook, nil
+}
+
+func (i *IncomingWebhookMongoRepository) GetIncomingWebhookByToken(tokenHash string) (*domain.IncomingWebhook, error) {
+	hook := &domain.IncomingWebhook{}
+	err := i.collection.FindOne(context.Background(), bson.M{"token_hash": tokenHash}).Decode(&hook)
+	if err != nil {
+		return nil, errors.New("incoming webhook not found")
+	}
+	return hook, nil
+}
+
+func (i *IncomingWebhookMongoRepository) GetIncomingWebhooks(conversationId string) ([]*domain.IncomingWebhook, error) {
+	hooks := []*domain.IncomingWebhook{}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	req, err := i.collection.Find(context.Background(), bson.M{"conversation_id": conversationId}, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("incoming webhooks not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var hook *domain.IncomingWebhook
+		if err := req.Decode(&hook); err != nil {
+			return nil, errors.New(fmt.Sprintf("incoming webhooks not found: %v", err.Error()))
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+func (i *IncomingWebhookMongoRepository) RotateIncomingWebhook(id, tokenHash string, rotatedAt time.Time) error {
+	update := bson.M{"$set": bson.M{"token_hash": tokenHash, "rotated_at": rotatedAt}}
+	req, err := i.collection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		return errors.New(fmt.Sprintf("incoming webhook not rotated: %v", err.Error()))
+	}
+	if req.MatchedCount == 0 {
+		return errors.New("incoming webhook not found")
+	}
+	return nil
+}
+
+func (i *IncomingWebhookMongoRepository) DeleteIncomingWebhook(id string) error {
+	req, err := i.collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete incoming webhook: %v", err.Error()))
+	}
+	if req.DeletedCount == 0 {
+		return errors.New("incoming webhook not found")
+	}
+	return nil
+}
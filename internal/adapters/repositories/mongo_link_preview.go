@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type LinkPreviewMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+}
+
+func NewLinkPreviewMongoRepository() *LinkPreviewMongoRepository {
+	client, collection := newMongoCollection("link_previews")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "message_id", Value: 1}, {Key: "url", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return &LinkPreviewMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+	}
+}
+
+func (l *LinkPreviewMongoRepository) SetLinkPreviews(messageId string, previews []*domain.LinkPreview) error {
+	if _, err := l.collection.DeleteMany(context.Background(), bson.M{"message_id": messageId}); err != nil {
+		return errors.New(fmt.Sprintf("link previews not saved: %v", err.Error()))
+	}
+
+	if len(previews) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0, len(previews))
+	for _, preview := range previews {
+		documents = append(documents, preview)
+	}
+	if _, err := l.collection.InsertMany(context.Background(), documents); err != nil {
+		return errors.New(fmt.Sprintf("link previews not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (l *LinkPreviewMongoRepository) GetLinkPreviews(messageIds []string) ([]*domain.LinkPreview, error) {
+	previews := []*domain.LinkPreview{}
+	if len(messageIds) == 0 {
+		return previews, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}})
+	req, err := l.collection.Find(context.Background(), bson.M{"message_id": bson.M{"$in": messageIds}}, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("link previews not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var preview *domain.LinkPreview
+		if err := req.Decode(&preview); err != nil {
+			return nil, errors.New(fmt.Sprintf("link previews not found: %v", err.Error()))
+		}
+		previews = append(previews, preview)
+	}
+	return previews, nil
+}
+
+func (l *LinkPreviewMongoRepository) DeleteLinkPreviews(messageIds []string) error {
+	_, err := l.collection.DeleteMany(context.Background(), bson.M{"message_id": bson.M{"$in": messageIds}})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete link previews: %v", err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type MentionMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+}
+
+func NewMentionMongoRepository() *MentionMongoRepository {
+	client, collection := newMongoCollection("mentions")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "message_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+	})
+
+	return &MentionMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+	}
+}
+
+func (m *MentionMongoRepository) SetMentions(messageId string, mentions []*domain.Mention) error {
+	_, err := m.collection.DeleteMany(context.Background(), bson.M{"message_id": messageId})
+	if err != nil {
+		return errors.New(fmt.Sprintf("mentions not saved: %v", err.Error()))
+	}
+
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0, len(mentions))
+	for _, mention := range mentions {
+		documents = append(documents, mention)
+	}
+
+	_, err = m.collection.InsertMany(context.Background(), documents)
+	if err != nil {
+		return errors.New(fmt.Sprintf("mentions not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (m *MentionMongoRepository) GetMentions(messageIds []string) ([]*domain.Mention, error) {
+	if len(messageIds) == 0 {
+		return []*domain.Mention{}, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "offset", Value: 1}})
+	return m.find(bson.M{"message_id": bson.M{"$in": messageIds}}, opts)
+}
+
+func (m *MentionMongoRepository) GetUserMentions(userId string, query domain.PageQuery) (*domain.MentionPage, error) {
+	cursor, forward, err := query.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	filter, opts := mongoKeysetPage(bson.M{"user_id": userId}, cursor, forward, query.Limit)
+	mentions, err := m.find(filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.MentionPage{Mentions: mentions}
+	if len(mentions) > query.Limit {
+		page.Mentions = mentions[:query.Limit]
+		last := page.Mentions[query.Limit-1]
+		page.NextCursor = domain.EncodeCursor(last.CreatedAt, last.Id)
+	}
+	if forward {
+		reverseMentions(page.Mentions)
+	}
+	return page, nil
+}
+
+func (m *MentionMongoRepository) DeleteMentions(messageIds []string) error {
+	_, err := m.collection.DeleteMany(context.Background(), bson.M{"message_id": bson.M{"$in": messageIds}})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete mentions: %v", err.Error()))
+	}
+	return nil
+}
+
+func (m *MentionMongoRepository) find(filter bson.M, opts *options.FindOptions) ([]*domain.Mention, error) {
+	mentions := []*domain.Mention{}
+	req, err := m.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("mentions not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var mention *domain.Mention
+		if err := req.Decode(&mention); err != nil {
+			return nil, errors.New(fmt.Sprintf("mentions not found: %v", err.Error()))
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions, nil
+}
@@ -4,9 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"go.mongodb.org/mongo-driver/mongo/readpref"
-	"log"
-	"strconv"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -21,39 +18,30 @@ type MessangerMongoRepository struct {
 	collection *mongo.Collection
 }
 
-func NewMessangerMongoRepository() *MessangerMongoRepository {
-
-	//err := godotenv.Load(".env")
-	//
-	//if err != nil {
-	//	log.Fatal("Error loading file .env")
-	//}
-
-	MongoUrl := "mongodb://0.0.0.0:27017"
-
-	MongodbTimeout := "20"
-
-	timeout, err := strconv.Atoi(MongodbTimeout)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
-
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(MongoUrl))
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	err = client.Ping(ctx, readpref.Primary())
-
-	if err != nil {
-		log.Fatal(err)
-	}
+type messageSearchDocument struct {
+	domain.Message `bson:",inline"`
+	Score          float64 `bson:"score"`
+}
 
-	collection := client.Database("management_messenger").Collection("messages")
+func NewMessangerMongoRepository() *MessangerMongoRepository {
+	client, collection := newMongoCollection("messages")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "conversation_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+	})
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "thread_root_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+	})
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "body", Value: "text"}},
+		Options: options.Index().SetDefaultLanguage("none"),
+	})
+	// expired messages are only removed by the expiry sweeper, which also cleans up
+	// their attachments, blobs and revisions; a TTL index would leave those behind
+	_, _ = collection.Indexes().DropOne(context.Background(), "expires_at_1")
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "expires_at", Value: 1}},
+	})
 
 	return &MessangerMongoRepository{
 		client:     client,
@@ -63,26 +51,39 @@ func NewMessangerMongoRepository() *MessangerMongoRepository {
 
 }
 
+// CreateMessage saves a reply and bumps its thread root. Without a transaction,
+// the reply is removed again when the root cannot be updated.
 func (m *MessangerMongoRepository) CreateMessage(message domain.Message) error {
 	_, err := m.collection.InsertOne(context.Background(), message)
 	if err != nil {
 		return errors.New(fmt.Sprintf("messages not saved: %v", err.Error()))
 	}
+
+	if message.ThreadRootId != "" {
+		if err := m.addThreadReply(message.ThreadRootId, message.CreatedAt); err != nil {
+			_, _ = m.collection.DeleteOne(context.Background(), bson.M{"_id": message.Id})
+			return err
+		}
+	}
 	return nil
 }
 
 func (m *MessangerMongoRepository) GetOneMessage(id string) (*domain.Message, error) {
 	message := &domain.Message{}
-	err := m.collection.FindOne(context.Background(), bson.M{"id": id}).Decode(&message)
+	err := m.collection.FindOne(context.Background(), mongoNotExpired(bson.M{"_id": id})).Decode(&message)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("message not found: %v", err.Error()))
 	}
 	return message, nil
 }
 
-func (m *MessangerMongoRepository) GetAllMessages() ([]*domain.Message, error) {
-	var messages []*domain.Message
-	req, err := m.collection.Find(context.Background(), bson.M{})
+func (m *MessangerMongoRepository) GetMessages(ids []string) ([]*domain.Message, error) {
+	messages := []*domain.Message{}
+	if len(ids) == 0 {
+		return messages, nil
+	}
+
+	req, err := m.collection.Find(context.Background(), mongoNotExpired(bson.M{"_id": bson.M{"$in": ids}}))
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("messages not found: %v", err.Error()))
 	}
@@ -98,10 +99,63 @@ func (m *MessangerMongoRepository) GetAllMessages() ([]*domain.Message, error) {
 	return messages, nil
 }
 
-func (m *MessangerMongoRepository) UpdateMessage(id, body, user_id string) (*domain.Message, error) {
+func (m *MessangerMongoRepository) GetAllMessages(filter domain.MessageFilter) (*domain.MessagePage, error) {
+	cursor, forward, err := filter.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"conversation_id": filter.ConversationId, "thread_root_id": bson.M{"$in": bson.A{"", nil}}}
+	if filter.ThreadRootId != "" {
+		query["thread_root_id"] = filter.ThreadRootId
+	}
+	if filter.UserId != "" {
+		query["user_id"] = filter.UserId
+	}
+	createdAt := bson.M{}
+	if !filter.Since.IsZero() {
+		createdAt["$gte"] = filter.Since
+	}
+	if !filter.Until.IsZero() {
+		createdAt["$lt"] = filter.Until
+	}
+	if len(createdAt) > 0 {
+		query["created_at"] = createdAt
+	}
+
+	query, opts := mongoKeysetPage(mongoNotExpired(query), cursor, forward, filter.Limit)
+
+	messages := []*domain.Message{}
+	req, err := m.collection.Find(context.Background(), query, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("messages not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var message *domain.Message
+		if err := req.Decode(&message); err != nil {
+			return nil, errors.New(fmt.Sprintf("messages not found: %v", err.Error()))
+		}
+		messages = append(messages, message)
+	}
+
+	page := &domain.MessagePage{Messages: messages}
+	if len(messages) > filter.Limit {
+		page.Messages = messages[:filter.Limit]
+		last := page.Messages[filter.Limit-1]
+		page.NextCursor = domain.EncodeCursor(last.CreatedAt, last.Id)
+	}
+	if forward {
+		reverseMessages(page.Messages)
+	}
+	return page, nil
+}
+
+func (m *MessangerMongoRepository) UpdateMessage(id, body, user_id string, editedAt time.Time) (*domain.Message, error) {
 	var message domain.Message
 
-	filter := bson.M{"id": id, "userid": user_id}
+	filter := bson.M{"_id": id, "user_id": user_id}
 
 	err := m.collection.FindOne(context.Background(), filter).Decode(&message)
 	if err != nil {
@@ -112,8 +166,16 @@ func (m *MessangerMongoRepository) UpdateMessage(id, body, user_id string) (*dom
 	}
 
 	message.Body = body
-
-	update := bson.M{"$set": bson.M{"body": message.Body}}
+	message.Edited = true
+	message.EditedAt = &editedAt
+	message.UpdatedAt = editedAt
+
+	update := bson.M{"$set": bson.M{
+		"body":       message.Body,
+		"edited":     message.Edited,
+		"edited_at":  message.EditedAt,
+		"updated_at": message.UpdatedAt,
+	}}
 	result, err := m.collection.UpdateOne(context.Background(), filter, update)
 
 	if err != nil {
@@ -128,27 +190,308 @@ func (m *MessangerMongoRepository) UpdateMessage(id, body, user_id string) (*dom
 
 }
 
-func (m *MessangerMongoRepository) DeleteMessage(id, user_id string) error {
-	var message domain.Message
+func (m *MessangerMongoRepository) addThreadReply(rootId string, repliedAt time.Time) error {
+	update := bson.M{
+		"$inc": bson.M{"reply_count": 1},
+		"$max": bson.M{"last_reply_at": repliedAt},
+	}
+	result, err := m.collection.UpdateOne(context.Background(), bson.M{"_id": rootId}, update)
+	if err != nil {
+		return errors.New(fmt.Sprintf("thread root not updated: %v", err.Error()))
+	}
 
-	filter := bson.M{"id": id, "userid": user_id}
+	if result.MatchedCount == 0 {
+		return errors.New("thread root not found")
+	}
+	return nil
+}
 
-	err := m.collection.FindOne(context.Background(), filter).Decode(&message)
+// CountMessagesSince counts, in one aggregation, the messages of each conversation
+// posted by others after the time given for it.
+func (m *MessangerMongoRepository) CountMessagesSince(excludeUserId string, since map[string]time.Time) (map[string]int, error) {
+	counts := make(map[string]int, len(since))
+	if len(since) == 0 {
+		return counts, nil
+	}
+
+	conditions := make(bson.A, 0, len(since))
+	for conversationId, at := range since {
+		conditions = append(conditions, bson.M{"conversation_id": conversationId, "created_at": bson.M{"$gt": at}})
+	}
+
+	filter := mongoNotExpired(bson.M{
+		"$or":        conditions,
+		"user_id":    bson.M{"$ne": excludeUserId},
+		"deleted_at": nil,
+	})
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{"_id": "$conversation_id", "unread": bson.M{"$sum": 1}}}},
+	}
+
+	req, err := m.collection.Aggregate(context.Background(), pipeline)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return errors.New(("message not found"))
+		return nil, errors.New(fmt.Sprintf("unable to count messages: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var row struct {
+			ConversationId string `bson:"_id"`
+			Unread         int    `bson:"unread"`
+		}
+		if err := req.Decode(&row); err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to count messages: %v", err.Error()))
 		}
-		return err
+		counts[row.ConversationId] = row.Unread
 	}
+	return counts, nil
+}
 
-	result, err := m.collection.DeleteOne(context.Background(), filter)
+func (m *MessangerMongoRepository) DeleteMessage(id, user_id string, deletedAt time.Time) error {
+	filter := bson.M{"_id": id, "user_id": user_id, "deleted_at": nil}
+	update := bson.M{"$set": bson.M{"deleted_at": deletedAt, "deleted_by": user_id}}
 
+	result, err := m.collection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
 		return errors.New("unable to delete message :(")
 	}
 
-	if result.DeletedCount < 1 {
-		return errors.New("Message with specified ID not found!")
+	if result.MatchedCount < 1 {
+		return errors.New("message not found")
+	}
+	return nil
+}
+
+func (m *MessangerMongoRepository) RestoreMessage(id, user_id string, deletedSince time.Time) error {
+	filter := bson.M{"_id": id, "deleted_by": user_id, "deleted_at": bson.M{"$gte": deletedSince}}
+	update := bson.M{"$set": bson.M{"deleted_at": nil, "deleted_by": ""}}
+
+	result, err := m.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return errors.New("unable to restore message :(")
+	}
+
+	if result.MatchedCount < 1 {
+		return errors.New("no deleted message to restore")
 	}
 	return nil
 }
+
+func (m *MessangerMongoRepository) PurgeDeletedMessages(deletedBefore time.Time) ([]string, error) {
+	req, err := m.collection.Find(context.Background(), bson.M{"deleted_at": bson.M{"$lt": deletedBefore}})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("deleted messages not found: %v", err.Error()))
+	}
+
+	var candidates []*domain.Message
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var message *domain.Message
+		if err := req.Decode(&message); err != nil {
+			return nil, errors.New(fmt.Sprintf("deleted messages not found: %v", err.Error()))
+		}
+		candidates = append(candidates, message)
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	candidateIds := make([]string, 0, len(candidates))
+	for _, message := range candidates {
+		candidateIds = append(candidateIds, message.Id)
+	}
+
+	// a thread root stays as a tombstone until its replies are purged, so threads never lose their root
+	roots, err := m.collection.Distinct(context.Background(), "thread_root_id", bson.M{"thread_root_id": bson.M{"$in": candidateIds}})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to purge messages: %v", err.Error()))
+	}
+
+	withReplies := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		if rootId, ok := root.(string); ok {
+			withReplies[rootId] = true
+		}
+	}
+
+	var ids []string
+	replies := make(map[string]int)
+	for _, message := range candidates {
+		if withReplies[message.Id] {
+			continue
+		}
+		ids = append(ids, message.Id)
+		if message.ThreadRootId != "" {
+			replies[message.ThreadRootId]++
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	if _, err := m.collection.DeleteMany(context.Background(), bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to purge messages: %v", err.Error()))
+	}
+
+	for rootId, count := range replies {
+		_, err := m.collection.UpdateOne(context.Background(), bson.M{"_id": rootId}, bson.M{"$inc": bson.M{"reply_count": -count}})
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to purge messages: %v", err.Error()))
+		}
+	}
+	return ids, nil
+}
+
+func (m *MessangerMongoRepository) SearchMessages(query domain.SearchQuery) (*domain.SearchPage, error) {
+	page := &domain.SearchPage{Results: []*domain.SearchResult{}}
+	if len(query.ConversationIds) == 0 {
+		return page, nil
+	}
+
+	filter := bson.M{
+		"$text":           bson.M{"$search": query.Query},
+		"conversation_id": bson.M{"$in": query.ConversationIds},
+		"deleted_at":      nil,
+	}
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(query.Offset)).
+		SetLimit(int64(query.Limit + 1))
+
+	req, err := m.collection.Find(context.Background(), mongoNotExpired(filter), opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("messages not found: %v", err.Error()))
+	}
+
+	var documents []*messageSearchDocument
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var document *messageSearchDocument
+		if err := req.Decode(&document); err != nil {
+			return nil, errors.New(fmt.Sprintf("messages not found: %v", err.Error()))
+		}
+		documents = append(documents, document)
+	}
+
+	if len(documents) > query.Limit {
+		documents = documents[:query.Limit]
+		page.NextCursor = domain.EncodeOffsetCursor(query.Offset + query.Limit)
+	}
+
+	for _, document := range documents {
+		message := document.Message
+		page.Results = append(page.Results, &domain.SearchResult{
+			Message: &message,
+			Rank:    document.Score,
+			Snippet: highlightSnippet(message.Body, query.Query),
+		})
+	}
+	return page, nil
+}
+
+// StartReadExpiry starts the countdown of "expire after read" messages once another
+// member has read them. $min keeps an earlier expiry from being pushed back.
+func (m *MessangerMongoRepository) StartReadExpiry(conversationId, readerId string, readUntil, readAt time.Time) error {
+	filter := bson.M{
+		"conversation_id":   conversationId,
+		"user_id":           bson.M{"$ne": readerId},
+		"created_at":        bson.M{"$lte": readUntil},
+		"expire_after_read": bson.M{"$gt": 0},
+		"deleted_at":        nil,
+	}
+	expiresAt := bson.M{"$add": bson.A{readAt, bson.M{"$multiply": bson.A{"$expire_after_read", 1000}}}}
+	// aggregation $min skips a missing expires_at, so this sets or lowers it
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"expires_at": bson.M{"$min": bson.A{"$expires_at", expiresAt}}}}},
+	}
+
+	_, err := m.collection.UpdateMany(context.Background(), filter, update)
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to start message expiry: %v", err.Error()))
+	}
+	return nil
+}
+
+func (m *MessangerMongoRepository) ExpireMessages(now time.Time) ([]*domain.Message, error) {
+	req, err := m.collection.Find(context.Background(), bson.M{"expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("expired messages not found: %v", err.Error()))
+	}
+
+	var messages []*domain.Message
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var message *domain.Message
+		if err := req.Decode(&message); err != nil {
+			return nil, errors.New(fmt.Sprintf("expired messages not found: %v", err.Error()))
+		}
+		messages = append(messages, message)
+	}
+
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.Id)
+	}
+
+	roots, err := m.collection.Distinct(context.Background(), "thread_root_id", bson.M{"thread_root_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to expire messages: %v", err.Error()))
+	}
+
+	withReplies := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		if rootId, ok := root.(string); ok {
+			withReplies[rootId] = true
+		}
+	}
+
+	var deleted []string
+	replies := make(map[string]int)
+	for _, message := range messages {
+		if withReplies[message.Id] {
+			// a thread root with replies keeps its place as an emptied tombstone
+			update := bson.M{
+				"$set":   bson.M{"body": "", "deleted_at": message.ExpiresAt, "deleted_by": "", "expire_after_read": 0},
+				"$unset": bson.M{"expires_at": ""},
+			}
+			if _, err := m.collection.UpdateOne(context.Background(), bson.M{"_id": message.Id}, update); err != nil {
+				return nil, errors.New(fmt.Sprintf("unable to expire messages: %v", err.Error()))
+			}
+			continue
+		}
+		deleted = append(deleted, message.Id)
+		if message.ThreadRootId != "" {
+			replies[message.ThreadRootId]++
+		}
+	}
+
+	if len(deleted) > 0 {
+		if _, err := m.collection.DeleteMany(context.Background(), bson.M{"_id": bson.M{"$in": deleted}}); err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to expire messages: %v", err.Error()))
+		}
+	}
+
+	for rootId, count := range replies {
+		_, err := m.collection.UpdateOne(context.Background(), bson.M{"_id": rootId}, bson.M{"$inc": bson.M{"reply_count": -count}})
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to expire messages: %v", err.Error()))
+		}
+	}
+	return messages, nil
+}
+
+// mongoNotExpired hides expired messages the sweeper has not removed yet;
+// $not also matches documents without an expiry.
+func mongoNotExpired(filter bson.M) bson.M {
+	filter["expires_at"] = bson.M{"$not": bson.M{"$lte": time.Now().UTC()}}
+	return filter
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type PinMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+}
+
+func NewPinMongoRepository() *PinMongoRepository {
+	client, collection := newMongoCollection("pins")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "conversation_id", Value: 1}, {Key: "message_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return &PinMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+	}
+}
+
+func (p *PinMongoRepository) AddPin(pin domain.Pin) error {
+	_, err := p.collection.InsertOne(context.Background(), pin)
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("message already pinned")
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("pin not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (p *PinMongoRepository) RemovePin(conversationId, messageId string) error {
+	req, err := p.collection.DeleteOne(context.Background(), bson.M{"conversation_id": conversationId, "message_id": messageId})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete pin: %v", err.Error()))
+	}
+	if req.DeletedCount == 0 {
+		return errors.New("pin not found")
+	}
+	return nil
+}
+
+func (p *PinMongoRepository) GetPins(conversationId string) ([]*domain.Pin, error) {
+	pins := []*domain.Pin{}
+	opts := options.Find().SetSort(bson.D{{Key: "pinned_at", Value: -1}})
+	req, err := p.collection.Find(context.Background(), bson.M{"conversation_id": conversationId}, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("pins not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var pin *domain.Pin
+		if err := req.Decode(&pin); err != nil {
+			return nil, errors.New(fmt.Sprintf("pins not found: %v", err.Error()))
+		}
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
+
+func (p *PinMongoRepository) DeletePins(messageIds []string) error {
+	_, err := p.collection.DeleteMany(context.Background(), bson.M{"message_id": bson.M{"$in": messageIds}})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete pins: %v", err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+const (
+	// pollVoteLease bounds how long a voter that died mid-write holds up ClosePoll
+	pollVoteLease      = 10 * time.Second
+	pollCloseRetryWait = 20 * time.Millisecond
+)
+
+// pollVoter marks a ballot being written, in the poll's "voting" list
+type pollVoter struct {
+	Token string    `bson:"token"`
+	Until time.Time `bson:"until"`
+}
+
+type PollMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+	votes      *mongo.Collection
+}
+
+func NewPollMongoRepository() *PollMongoRepository {
+	client, collection := newMongoCollection("polls")
+
+	votes := client.Database(MongoDatabase).Collection("poll_votes")
+	_, _ = votes.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "message_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return &PollMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+		votes:      votes,
+	}
+}
+
+func (p *PollMongoRepository) CreatePoll(poll domain.Poll) error {
+	_, err := p.collection.InsertOne(context.Background(), poll)
+	if err != nil {
+		return errors.New(fmt.Sprintf("poll not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (p *PollMongoRepository) GetPolls(messageIds []string) ([]*domain.Poll, error) {
+	polls := []*domain.Poll{}
+	if len(messageIds) == 0 {
+		return polls, nil
+	}
+
+	req, err := p.collection.Find(context.Background(), bson.M{"_id": bson.M{"$in": messageIds}})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("polls not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var poll *domain.Poll
+		if err := req.Decode(&poll); err != nil {
+			return nil, errors.New(fmt.Sprintf("polls not found: %v", err.Error()))
+		}
+		polls = append(polls, poll)
+	}
+	return polls, nil
+}
+
+// ClosePoll waits for the ballots being written to land before it closes the
+// poll, so no ballot is written after it.
+func (p *PollMongoRepository) ClosePoll(messageId string, closedAt time.Time) error {
+	deadline := time.Now().Add(pollVoteLease)
+	for {
+		filter := bson.M{
+			"_id":       messageId,
+			"closed_at": nil,
+			"voting":    bson.M{"$not": bson.M{"$elemMatch": bson.M{"until": bson.M{"$gt": time.Now().UTC()}}}},
+		}
+		update := bson.M{"$set": bson.M{"closed_at": closedAt}, "$unset": bson.M{"voting": ""}}
+		req, err := p.collection.UpdateOne(context.Background(), filter, update)
+		if err != nil {
+			return errors.New(fmt.Sprintf("poll not closed: %v", err.Error()))
+		}
+		if req.MatchedCount > 0 {
+			return nil
+		}
+
+		open, err := p.collection.CountDocuments(context.Background(), bson.M{"_id": messageId, "closed_at": nil})
+		if err != nil {
+			return errors.New(fmt.Sprintf("poll not closed: %v", err.Error()))
+		}
+		if open == 0 {
+			return errors.New("poll is already closed")
+		}
+		if time.Now().After(deadline) {
+			return errors.New("poll not closed: votes are still being cast")
+		}
+		time.Sleep(pollCloseRetryWait)
+	}
+}
+
+// beginVote registers a ballot being written on the poll, as long as the poll is
+// still open at now. Votes and polls live in different documents, so this is what
+// keeps ClosePoll from slipping in between the check and the write.
+func (p *PollMongoRepository) beginVote(messageId string, now time.Time) (string, error) {
+	voter := pollVoter{Token: uuid.New().String(), Until: time.Now().UTC().Add(pollVoteLease)}
+	filter := bson.M{
+		"_id":       messageId,
+		"closed_at": nil,
+		"$or": bson.A{
+			bson.M{"closes_at": nil},
+			bson.M{"closes_at": bson.M{"$gt": now}},
+		},
+	}
+	req, err := p.collection.UpdateOne(context.Background(), filter, bson.M{"$push": bson.M{"voting": voter}})
+	if err != nil {
+		return "", err
+	}
+	if req.MatchedCount == 0 {
+		return "", domain.ErrPollClosed
+	}
+	return voter.Token, nil
+}
+
+// endVote is best effort: a marker left behind expires after pollVoteLease
+func (p *PollMongoRepository) endVote(messageId, token string) {
+	_, _ = p.collection.UpdateOne(context.Background(), bson.M{"_id": messageId}, bson.M{"$pull": bson.M{"voting": bson.M{"token": token}}})
+}
+
+// CastVote replaces the user's ballot in a single document write; the unique
+// index on (message_id, user_id) keeps it to one ballot per user.
+func (p *PollMongoRepository) CastVote(vote domain.PollVote) error {
+	token, err := p.beginVote(vote.MessageId, vote.UpdatedAt)
+	if err == domain.ErrPollClosed {
+		return err
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("vote not saved: %v", err.Error()))
+	}
+	defer p.endVote(vote.MessageId, token)
+
+	filter := bson.M{"message_id": vote.MessageId, "user_id": vote.UserId}
+	update := bson.M{
+		"$set":         bson.M{"option_ids": vote.OptionIds, "updated_at": vote.UpdatedAt},
+		"$setOnInsert": bson.M{"created_at": vote.CreatedAt},
+	}
+
+	req, err := p.votes.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// a concurrent first vote won the insert, so ours becomes an update
+		req, err = p.votes.UpdateOne(context.Background(), filter, update)
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("vote not saved: %v", err.Error()))
+	}
+	if req.MatchedCount == 0 && req.UpsertedCount == 0 {
+		return errors.New("vote not saved: ballot not written")
+	}
+	return nil
+}
+
+func (p *PollMongoRepository) RemoveVote(messageId, userId string, now time.Time) error {
+	token, err := p.beginVote(messageId, now)
+	if err == domain.ErrPollClosed {
+		return err
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete vote: %v", err.Error()))
+	}
+	defer p.endVote(messageId, token)
+
+	req, err := p.votes.DeleteOne(context.Background(), bson.M{"message_id": messageId, "user_id": userId})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete vote: %v", err.Error()))
+	}
+	if req.DeletedCount == 0 {
+		return errors.New("vote not found")
+	}
+	return nil
+}
+
+func (p *PollMongoRepository) GetVotes(messageIds []string) ([]*domain.PollVote, error) {
+	votes := []*domain.PollVote{}
+	if len(messageIds) == 0 {
+		return votes, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	req, err := p.votes.Find(context.Background(), bson.M{"message_id": bson.M{"$in": messageIds}}, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("votes not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var vote *domain.PollVote
+		if err := req.Decode(&vote); err != nil {
+			return nil, errors.New(fmt.Sprintf("votes not found: %v", err.Error()))
+		}
+		votes = append(votes, vote)
+	}
+	return votes, nil
+}
+
+func (p *PollMongoRepository) DeletePolls(messageIds []string) error {
+	if _, err := p.votes.DeleteMany(context.Background(), bson.M{"message_id": bson.M{"$in": messageIds}}); err != nil {
+		return errors.New(fmt.Sprintf("unable to delete polls: %v", err.Error()))
+	}
+	if _, err := p.collection.DeleteMany(context.Background(), bson.M{"_id": bson.M{"$in": messageIds}}); err != nil {
+		return errors.New(fmt.Sprintf("unable to delete polls: %v", err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type ReactionMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+}
+
+func NewReactionMongoRepository() *ReactionMongoRepository {
+	client, collection := newMongoCollection("reactions")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "message_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "emoji", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return &ReactionMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+	}
+}
+
+func (r *ReactionMongoRepository) AddReaction(reaction domain.Reaction) error {
+	_, err := r.collection.InsertOne(context.Background(), reaction)
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("user already reacted with this emoji")
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("reaction not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (r *ReactionMongoRepository) RemoveReaction(messageId, userId, emoji string) error {
+	filter := bson.M{"message_id": messageId, "user_id": userId, "emoji": emoji}
+	result, err := r.collection.DeleteOne(context.Background(), filter)
+	if err != nil {
+		return errors.New("unable to delete reaction :(")
+	}
+
+	if result.DeletedCount < 1 {
+		return errors.New("reaction not found")
+	}
+	return nil
+}
+
+// GetReactionSummaries counts the reactions per message and emoji, in the order
+// each emoji was first used.
+func (r *ReactionMongoRepository) GetReactionSummaries(messageIds []string, userId string) ([]*domain.ReactionSummary, error) {
+	summaries := []*domain.ReactionSummary{}
+	if len(messageIds) == 0 {
+		return summaries, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"message_id": bson.M{"$in": messageIds}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     bson.M{"message_id": "$message_id", "emoji": "$emoji"},
+			"count":   bson.M{"$sum": 1},
+			"reacted": bson.M{"$max": bson.M{"$eq": bson.A{"$user_id", userId}}},
+			"first":   bson.M{"$min": "$created_at"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "first", Value: 1}}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":        0,
+			"message_id": "$_id.message_id",
+			"emoji":      "$_id.emoji",
+			"count":      1,
+			"reacted":    1,
+		}}},
+	}
+
+	req, err := r.collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("reactions not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var summary *domain.ReactionSummary
+		if err := req.Decode(&summary); err != nil {
+			return nil, errors.New(fmt.Sprintf("reactions not found: %v", err.Error()))
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+func (r *ReactionMongoRepository) DeleteReactions(messageIds []string) error {
+	_, err := r.collection.DeleteMany(context.Background(), bson.M{"message_id": bson.M{"$in": messageIds}})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete reactions: %v", err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type ReadMarkerMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+}
+
+func NewReadMarkerMongoRepository() *ReadMarkerMongoRepository {
+	client, collection := newMongoCollection("read_markers")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "conversation_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return &ReadMarkerMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+	}
+}
+
+func (r *ReadMarkerMongoRepository) SetReadMarker(marker domain.ReadMarker) (bool, error) {
+	filter := bson.M{
+		"conversation_id": marker.ConversationId,
+		"user_id":         marker.UserId,
+		"last_read_at":    bson.M{"$lt": marker.LastReadAt},
+	}
+	update := bson.M{"$set": bson.M{
+		"last_read_message_id": marker.LastReadMessageId,
+		"last_read_at":         marker.LastReadAt,
+		"updated_at":           marker.UpdatedAt,
+	}}
+
+	// a newer marker already stored makes the filter miss and the upsert collide with the unique index
+	result, err := r.collection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("read marker not saved: %v", err.Error()))
+	}
+	return result.ModifiedCount > 0 || result.UpsertedCount > 0, nil
+}
+
+func (r *ReadMarkerMongoRepository) GetReadMarkers(conversationId string) ([]*domain.ReadMarker, error) {
+	return r.find(bson.M{"conversation_id": conversationId})
+}
+
+func (r *ReadMarkerMongoRepository) GetUserReadMarkers(userId string) ([]*domain.ReadMarker, error) {
+	return r.find(bson.M{"user_id": userId})
+}
+
+func (r *ReadMarkerMongoRepository) find(filter bson.M) ([]*domain.ReadMarker, error) {
+	var markers []*domain.ReadMarker
+	req, err := r.collection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("read markers not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var marker *domain.ReadMarker
+		if err := req.Decode(&marker); err != nil {
+			return nil, errors.New(fmt.Sprintf("read markers not found: %v", err.Error()))
+		}
+		markers = append(markers, marker)
+	}
+	return markers, nil
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type ReceiptMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+}
+
+func NewReceiptMongoRepository() *ReceiptMongoRepository {
+	client, collection := newMongoCollection("delivery_markers")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "conversation_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return &ReceiptMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+	}
+}
+
+func (r *ReceiptMongoRepository) SetDeliveryMarker(marker domain.DeliveryMarker) (bool, error) {
+	filter := bson.M{
+		"conversation_id":   marker.ConversationId,
+		"user_id":           marker.UserId,
+		"last_delivered_at": bson.M{"$lt": marker.LastDeliveredAt},
+	}
+	update := bson.M{"$set": bson.M{
+		"last_delivered_message_id": marker.LastDeliveredMessageId,
+		"last_delivered_at":         marker.LastDeliveredAt,
+		"updated_at":                marker.UpdatedAt,
+	}}
+
+	// a newer marker already stored makes the filter miss and the upsert collide with the unique index
+	result, err := r.collection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("delivery marker not saved: %v", err.Error()))
+	}
+	return result.ModifiedCount > 0 || result.UpsertedCount > 0, nil
+}
+
+func (r *ReceiptMongoRepository) GetDeliveryMarkers(conversationId string) ([]*domain.DeliveryMarker, error) {
+	var markers []*domain.DeliveryMarker
+	req, err := r.collection.Find(context.Background(), bson.M{"conversation_id": conversationId})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("delivery markers not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var marker *domain.DeliveryMarker
+		if err := req.Decode(&marker); err != nil {
+			return nil, errors.New(fmt.Sprintf("delivery markers not found: %v", err.Error()))
+		}
+		markers = append(markers, marker)
+	}
+	return markers, nil
+}
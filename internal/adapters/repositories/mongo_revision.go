@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type RevisionMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+}
+
+func NewRevisionMongoRepository() *RevisionMongoRepository {
+	client, collection := newMongoCollection("message_revisions")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "message_id", Value: 1}, {Key: "edited_at", Value: 1}},
+	})
+
+	return &RevisionMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+	}
+}
+
+func (r *RevisionMongoRepository) AddRevision(revision domain.MessageRevision) error {
+	_, err := r.collection.InsertOne(context.Background(), revision)
+	if err != nil {
+		return errors.New(fmt.Sprintf("revision not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (r *RevisionMongoRepository) GetRevisions(messageId string) ([]*domain.MessageRevision, error) {
+	var revisions []*domain.MessageRevision
+	opts := options.Find().SetSort(bson.D{{Key: "edited_at", Value: 1}})
+	req, err := r.collection.Find(context.Background(), bson.M{"message_id": messageId}, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("revisions not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var revision *domain.MessageRevision
+		if err := req.Decode(&revision); err != nil {
+			return nil, errors.New(fmt.Sprintf("revisions not found: %v", err.Error()))
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func (r *RevisionMongoRepository) DeleteRevision(id string) error {
+	_, err := r.collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete revision: %v", err.Error()))
+	}
+	return nil
+}
+
+func (r *RevisionMongoRepository) DeleteRevisions(messageIds []string) error {
+	_, err := r.collection.DeleteMany(context.Background(), bson.M{"message_id": bson.M{"$in": messageIds}})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete revisions: %v", err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type ScheduledMessageMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+}
+
+func NewScheduledMessageMongoRepository() *ScheduledMessageMongoRepository {
+	client, collection := newMongoCollection("scheduled_messages")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "send_at", Value: 1}},
+	})
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}},
+	})
+
+	return &ScheduledMessageMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+	}
+}
+
+func (s *ScheduledMessageMongoRepository) AddScheduledMessage(scheduled domain.ScheduledMessage) error {
+	_, err := s.collection.InsertOne(context.Background(), scheduled)
+	if err != nil {
+		return errors.New(fmt.Sprintf("scheduled message not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (s *ScheduledMessageMongoRepository) GetScheduledMessages(userId, conversationId string) ([]*domain.ScheduledMessage, error) {
+	filter := bson.M{
+		"user_id": userId,
+		"status":  bson.M{"$in": bson.A{domain.ScheduledPending, domain.ScheduledFailed}},
+	}
+	if conversationId != "" {
+		filter["conversation_id"] = conversationId
+	}
+
+	scheduled := []*domain.ScheduledMessage{}
+	opts := options.Find().SetSort(bson.D{{Key: "send_at", Value: 1}})
+	req, err := s.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("scheduled messages not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var message *domain.ScheduledMessage
+		if err := req.Decode(&message); err != nil {
+			return nil, errors.New(fmt.Sprintf("scheduled messages not found: %v", err.Error()))
+		}
+		scheduled = append(scheduled, message)
+	}
+	return scheduled, nil
+}
+
+func (s *ScheduledMessageMongoRepository) UpdateScheduledMessage(id, userId, body string, sendAt, updatedAt time.Time) (*domain.ScheduledMessage, error) {
+	filter := bson.M{
+		"_id":     id,
+		"user_id": userId,
+		"status":  bson.M{"$in": bson.A{domain.ScheduledPending, domain.ScheduledFailed}},
+	}
+	update := bson.M{"$set": bson.M{
+		"body":       body,
+		"send_at":    sendAt,
+		"status":     domain.ScheduledPending,
+		"error":      "",
+		"updated_at": updatedAt,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	scheduled := &domain.ScheduledMessage{}
+	err := s.collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&scheduled)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("scheduled message not found or already sent")
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("scheduled message not updated: %v", err.Error()))
+	}
+	return scheduled, nil
+}
+
+func (s *ScheduledMessageMongoRepository) CancelScheduledMessage(id, userId string, cancelledAt time.Time) error {
+	filter := bson.M{
+		"_id":     id,
+		"user_id": userId,
+		"status":  bson.M{"$in": bson.A{domain.ScheduledPending, domain.ScheduledFailed}},
+	}
+	update := bson.M{"$set": bson.M{"status": domain.ScheduledCancelled, "updated_at": cancelledAt}}
+
+	req, err := s.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return errors.New(fmt.Sprintf("scheduled message not cancelled: %v", err.Error()))
+	}
+	if req.MatchedCount == 0 {
+		return errors.New("scheduled message not found or already sent")
+	}
+	return nil
+}
+
+// ClaimDueMessages leases due messages one at a time with findOneAndUpdate, which is
+// atomic per document, so two instances never claim the same message.
+func (s *ScheduledMessageMongoRepository) ClaimDueMessages(now time.Time, owner string, lease time.Duration, limit int) ([]*domain.ScheduledMessage, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"status": domain.ScheduledPending, "send_at": bson.M{"$lte": now}},
+		bson.M{"status": domain.ScheduledSending, "claimed_until": bson.M{"$lt": now}},
+	}}
+	update := bson.M{"$set": bson.M{
+		"status":        domain.ScheduledSending,
+		"claimed_by":    owner,
+		"claimed_until": now.Add(lease),
+		"updated_at":    now,
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "send_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	scheduled := []*domain.ScheduledMessage{}
+	for len(scheduled) < limit {
+		message := &domain.ScheduledMessage{}
+		err := s.collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&message)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return scheduled, errors.New(fmt.Sprintf("scheduled messages not claimed: %v", err.Error()))
+		}
+		scheduled = append(scheduled, message)
+	}
+	return scheduled, nil
+}
+
+func (s *ScheduledMessageMongoRepository) FinishScheduledMessage(id, owner, status, errorMessage string, finishedAt time.Time) error {
+	set := bson.M{
+		"status":        status,
+		"error":         errorMessage,
+		"claimed_until": nil,
+		"updated_at":    finishedAt,
+	}
+	if status == domain.ScheduledSent {
+		set["sent_at"] = finishedAt
+	}
+
+	filter := bson.M{"_id": id, "claimed_by": owner, "status": domain.ScheduledSending}
+	req, err := s.collection.UpdateOne(context.Background(), filter, bson.M{"$set": set})
+	if err != nil {
+		return errors.New(fmt.Sprintf("scheduled message not updated: %v", err.Error()))
+	}
+	if req.MatchedCount == 0 {
+		return errors.New("scheduled message is no longer claimed")
+	}
+	return nil
+}
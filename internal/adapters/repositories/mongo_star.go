@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type StarMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+}
+
+func NewStarMongoRepository() *StarMongoRepository {
+	client, collection := newMongoCollection("stars")
+
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "message_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+	})
+
+	return &StarMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+	}
+}
+
+func (s *StarMongoRepository) SetStar(star domain.Star) (*domain.Star, error) {
+	filter := bson.M{"user_id": star.UserId, "message_id": star.MessageId}
+	update := bson.M{
+		"$set": bson.M{"note": star.Note, "updated_at": star.UpdatedAt},
+		"$setOnInsert": bson.M{
+			"_id":             star.Id,
+			"conversation_id": star.ConversationId,
+			"created_at":      star.CreatedAt,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	saved := &domain.Star{}
+	err := s.collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&saved)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("star not saved: %v", err.Error()))
+	}
+	return saved, nil
+}
+
+func (s *StarMongoRepository) RemoveStar(userId, messageId string) error {
+	req, err := s.collection.DeleteOne(context.Background(), bson.M{"user_id": userId, "message_id": messageId})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete star: %v", err.Error()))
+	}
+	if req.DeletedCount == 0 {
+		return errors.New("star not found")
+	}
+	return nil
+}
+
+func (s *StarMongoRepository) GetStars(userId string, query domain.PageQuery) (*domain.StarPage, error) {
+	cursor, forward, err := query.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	filter, opts := mongoKeysetPage(bson.M{"user_id": userId}, cursor, forward, query.Limit)
+
+	stars := []*domain.Star{}
+	req, err := s.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("stars not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var star *domain.Star
+		if err := req.Decode(&star); err != nil {
+			return nil, errors.New(fmt.Sprintf("stars not found: %v", err.Error()))
+		}
+		stars = append(stars, star)
+	}
+
+	page := &domain.StarPage{Stars: stars}
+	if len(stars) > query.Limit {
+		page.Stars = stars[:query.Limit]
+		last := page.Stars[query.Limit-1]
+		page.NextCursor = domain.EncodeCursor(last.CreatedAt, last.Id)
+	}
+	if forward {
+		reverseStars(page.Stars)
+	}
+	return page, nil
+}
+
+func (s *StarMongoRepository) DeleteStars(messageIds []string) error {
+	_, err := s.collection.DeleteMany(context.Background(), bson.M{"message_id": bson.M{"$in": messageIds}})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete stars: %v", err.Error()))
+	}
+	return nil
+}
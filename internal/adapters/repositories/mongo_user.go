@@ -4,15 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"go.mongodb.org/mongo-driver/mongo/readpref"
-	"log"
 	"os"
-	"strconv"
+	"regexp"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
@@ -26,38 +25,16 @@ type UserMongoRepository struct {
 }
 
 func NewUserMongoRepository() *UserMongoRepository {
+	client, collection := newMongoCollection("users")
 
-	//err := godotenv.Load(".env")
-	//
-	//if err != nil {
-	//	log.Fatal("Error loading file .env")
-	//}
-
-	MongoUrl := "mongodb://0.0.0.0:27017"
-
-	MongodbTimeout := "20"
-
-	timeout, err := strconv.Atoi(MongodbTimeout)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
-
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(MongoUrl))
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	err = client.Ping(ctx, readpref.Primary())
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	collection := client.Database("management_messenger").Collection("users")
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+	})
+	_, _ = collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "handle", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"handle": bson.M{"$gt": ""}}),
+	})
 
 	return &UserMongoRepository{
 		client:     client,
@@ -88,16 +65,23 @@ func (u *UserMongoRepository) RegisterUser(user domain.User) error {
 
 func (u *UserMongoRepository) GetOneUser(id string) (*domain.User, error) {
 	user := &domain.User{}
-	err := u.collection.FindOne(context.Background(), bson.M{"id": id}).Decode(&user)
+	err := u.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&user)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("user not found: %v", err.Error()))
 	}
 	return user, nil
 }
 
-func (u *UserMongoRepository) GetAllUsers() ([]*domain.User, error) {
-	var users []*domain.User
-	req, err := u.collection.Find(context.Background(), bson.M{})
+func (u *UserMongoRepository) GetAllUsers(query domain.PageQuery) (*domain.UserPage, error) {
+	cursor, forward, err := query.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	filter, opts := mongoKeysetPage(bson.M{}, cursor, forward, query.Limit)
+
+	users := []*domain.User{}
+	req, err := u.collection.Find(context.Background(), filter, opts)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("users not found: %v", err.Error()))
 	}
@@ -110,7 +94,17 @@ func (u *UserMongoRepository) GetAllUsers() ([]*domain.User, error) {
 		}
 		users = append(users, user)
 	}
-	return users, nil
+
+	page := &domain.UserPage{Users: users}
+	if len(users) > query.Limit {
+		page.Users = users[:query.Limit]
+		last := page.Users[query.Limit-1]
+		page.NextCursor = domain.EncodeCursor(last.CreatedAt, last.Id)
+	}
+	if forward {
+		reverseUsers(page.Users)
+	}
+	return page, nil
 }
 
 func (u *UserMongoRepository) LoginUser(email, password string) (*LoginResponse, error) {
@@ -150,7 +144,7 @@ func (u *UserMongoRepository) LoginUser(email, password string) (*LoginResponse,
 func (u *UserMongoRepository) UpdateUser(id, email, password string) (*domain.User, error) {
 	var user domain.User
 
-	err := u.collection.FindOne(context.Background(), bson.M{"id": id}).Decode(&user)
+	err := u.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&user)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("user not found: %v", err.Error()))
 	}
@@ -164,7 +158,7 @@ func (u *UserMongoRepository) UpdateUser(id, email, password string) (*domain.Us
 	user.Email = email
 
 	update := bson.M{"email": user.Email, "password": user.Password}
-	result, err := u.collection.UpdateOne(context.Background(), bson.M{"id": id}, bson.M{"$set": update})
+	result, err := u.collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": update})
 
 	if err != nil {
 		return nil, errors.New("unable to update user :(")
@@ -172,7 +166,7 @@ func (u *UserMongoRepository) UpdateUser(id, email, password string) (*domain.Us
 
 	var updatedUser domain.User
 	if result.MatchedCount == 1 {
-		err := u.collection.FindOne(context.Background(), bson.M{"id": id}).Decode(&updatedUser)
+		err := u.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&updatedUser)
 		if err != nil {
 			return nil, errors.New("unable to found updated user :(")
 		}
@@ -184,7 +178,7 @@ func (u *UserMongoRepository) UpdateUser(id, email, password string) (*domain.Us
 
 func (u *UserMongoRepository) DeleteUser(id string) error {
 
-	result, err := u.collection.DeleteOne(context.Background(), bson.M{"id": id})
+	result, err := u.collection.DeleteOne(context.Background(), bson.M{"_id": id})
 
 	if err != nil {
 		return errors.New("unable to delete user :(")
@@ -227,3 +221,55 @@ func (u *UserMongoRepository) UserMongoExist(email string) error {
 	}
 	return nil
 }
+
+func (u *UserMongoRepository) GetUsersByEmails(emails []string) ([]*domain.User, error) {
+	if len(emails) == 0 {
+		return []*domain.User{}, nil
+	}
+
+	patterns := bson.A{}
+	for _, email := range emails {
+		patterns = append(patterns, primitive.Regex{Pattern: "^" + regexp.QuoteMeta(email) + "$", Options: "i"})
+	}
+	return u.findUsers(bson.M{"email": bson.M{"$in": patterns}})
+}
+
+func (u *UserMongoRepository) GetUsersByHandles(handles []string) ([]*domain.User, error) {
+	if len(handles) == 0 {
+		return []*domain.User{}, nil
+	}
+	return u.findUsers(bson.M{"handle": bson.M{"$in": handles}})
+}
+
+func (u *UserMongoRepository) GetUsers(ids []string) ([]*domain.User, error) {
+	if len(ids) == 0 {
+		return []*domain.User{}, nil
+	}
+	return u.findUsers(bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (u *UserMongoRepository) SetLastSeen(id string, lastSeenAt time.Time) error {
+	_, err := u.collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"last_seen_at": lastSeenAt}})
+	if err != nil {
+		return errors.New(fmt.Sprintf("last seen not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (u *UserMongoRepository) findUsers(filter bson.M) ([]*domain.User, error) {
+	users := []*domain.User{}
+	req, err := u.collection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("users not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var user *domain.User
+		if err := req.Decode(&user); err != nil {
+			return nil, errors.New(fmt.Sprintf("users not found: %v", err.Error()))
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
@@ -0,0 +1,241 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+type WebhookMongoRepository struct {
+	client     *mongo.Client
+	db         string
+	collection *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+func NewWebhookMongoRepository() *WebhookMongoRepository {
+	client, collection := newMongoCollection("webhooks")
+
+	deliveries := client.Database(MongoDatabase).Collection("webhook_deliveries")
+	_, _ = deliveries.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+	})
+	_, _ = deliveries.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	_, _ = deliveries.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "message_id", Value: 1}},
+	})
+
+	return &WebhookMongoRepository{
+		client:     client,
+		db:         MongoUrl,
+		collection: collection,
+		deliveries: deliveries,
+	}
+}
+
+func (w *WebhookMongoRepository) AddWebhook(webhook domain.Webhook) error {
+	_, err := w.collection.InsertOne(context.Background(), webhook)
+	if err != nil {
+		return errors.New(fmt.Sprintf("webhook not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (w *WebhookMongoRepository) GetWebhooks() ([]*domain.Webhook, error) {
+	webhooks := []*domain.Webhook{}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	req, err := w.collection.Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("webhooks not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var webhook *domain.Webhook
+		if err := req.Decode(&webhook); err != nil {
+			return nil, errors.New(fmt.Sprintf("webhooks not found: %v", err.Error()))
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+func (w *WebhookMongoRepository) GetWebhook(id string) (*domain.Webhook, error) {
+	webhook := &domain.Webhook{}
+	err := w.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&webhook)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("webhook not found: %v", err.Error()))
+	}
+	return webhook, nil
+}
+
+func (w *WebhookMongoRepository) DeleteWebhook(id string) error {
+	req, err := w.collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete webhook: %v", err.Error()))
+	}
+	if req.DeletedCount == 0 {
+		return errors.New("webhook not found")
+	}
+
+	_, err = w.deliveries.DeleteMany(context.Background(), bson.M{"webhook_id": id})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to delete webhook deliveries: %v", err.Error()))
+	}
+	return nil
+}
+
+func (w *WebhookMongoRepository) AddDeliveries(deliveries []domain.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		documents = append(documents, delivery)
+	}
+
+	// a retried batch finds the deliveries it already stored and skips them
+	_, err := w.deliveries.InsertMany(context.Background(), documents, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return errors.New(fmt.Sprintf("webhook deliveries not saved: %v", err.Error()))
+	}
+	return nil
+}
+
+func (w *WebhookMongoRepository) GetDeliveries(webhookId, status string, limit int) ([]*domain.WebhookDelivery, error) {
+	filter := bson.M{"webhook_id": webhookId}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	deliveries := []*domain.WebhookDelivery{}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+	req, err := w.deliveries.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("webhook deliveries not found: %v", err.Error()))
+	}
+
+	defer req.Close(context.Background())
+	for req.Next(context.Background()) {
+		var delivery *domain.WebhookDelivery
+		if err := req.Decode(&delivery); err != nil {
+			return nil, errors.New(fmt.Sprintf("webhook deliveries not found: %v", err.Error()))
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries leases due deliveries one at a time with findOneAndUpdate,
+// the same way scheduled messages are claimed.
+func (w *WebhookMongoRepository) ClaimDueDeliveries(now time.Time, owner string, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"status": domain.WebhookPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"status": domain.WebhookSending, "claimed_until": bson.M{"$lt": now}},
+	}}
+	update := bson.M{"$set": bson.M{
+		"status":        domain.WebhookSending,
+		"claimed_by":    owner,
+		"claimed_until": now.Add(lease),
+		"updated_at":    now,
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	deliveries := []*domain.WebhookDelivery{}
+	for len(deliveries) < limit {
+		delivery := &domain.WebhookDelivery{}
+		err := w.deliveries.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&delivery)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return deliveries, errors.New(fmt.Sprintf("webhook deliveries not claimed: %v", err.Error()))
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (w *WebhookMongoRepository) FinishDelivery(delivery domain.WebhookDelivery, owner string) error {
+	filter := bson.M{"_id": delivery.Id, "claimed_by": owner, "status": domain.WebhookSending}
+	update := bson.M{"$set": bson.M{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"response_status": delivery.ResponseStatus,
+		"error":           delivery.Error,
+		"payload":         delivery.Payload,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"delivered_at":    delivery.DeliveredAt,
+		"claimed_until":   nil,
+		"updated_at":      delivery.UpdatedAt,
+	}}
+
+	req, err := w.deliveries.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return errors.New(fmt.Sprintf("webhook delivery not updated: %v", err.Error()))
+	}
+	if req.MatchedCount == 0 {
+		return errors.New("webhook delivery is no longer claimed")
+	}
+	return nil
+}
+
+func (w *WebhookMongoRepository) RetryDelivery(id, webhookId string, now time.Time) error {
+	filter := bson.M{"_id": id, "webhook_id": webhookId, "status": domain.WebhookDead}
+	update := bson.M{"$set": bson.M{
+		"status":          domain.WebhookPending,
+		"attempts":        0,
+		"error":           "",
+		"next_attempt_at": now,
+		"updated_at":      now,
+	}}
+
+	req, err := w.deliveries.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return errors.New(fmt.Sprintf("webhook delivery not updated: %v", err.Error()))
+	}
+	if req.MatchedCount == 0 {
+		return errors.New("webhook delivery not found or not dead-lettered")
+	}
+	return nil
+}
+
+// PruneDeliveries removes delivered and dead-lettered deliveries last touched before a time.
+func (w *WebhookMongoRepository) PruneDeliveries(before time.Time) (int, error) {
+	filter := bson.M{
+		"status":     bson.M{"$in": []string{domain.WebhookDelivered, domain.WebhookDead}},
+		"updated_at": bson.M{"$lt": before},
+	}
+	req, err := w.deliveries.DeleteMany(context.Background(), filter)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("unable to prune webhook deliveries: %v", err.Error()))
+	}
+	return int(req.DeletedCount), nil
+}
+
+// DeleteMessageDeliveries drops the undelivered payloads of messages that no longer exist.
+func (w *WebhookMongoRepository) DeleteMessageDeliveries(messageIds []string) error {
+	if len(messageIds) == 0 {
+		return nil
+	}
+
+	filter := bson.M{
+		"message_id": bson.M{"$in": messageIds},
+		"status":     bson.M{"$in": []string{domain.WebhookPending, domain.WebhookDead}},
+	}
+	if _, err := w.deliveries.DeleteMany(context.Background(), filter); err != nil {
+		return errors.New(fmt.Sprintf("unable to delete webhook deliveries: %v", err.Error()))
+	}
+	return nil
+}
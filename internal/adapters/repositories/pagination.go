@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"github.com/jinzhu/gorm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"messenger/internal/core/domain"
+)
+
+func keysetPage(query *gorm.DB, cursor *domain.Cursor, forward bool, limit int) *gorm.DB {
+	if forward {
+		if cursor != nil {
+			query = query.Where("(created_at, id) > (?, ?)", cursor.CreatedAt, cursor.Id)
+		}
+		return query.Order("created_at ASC, id ASC").Limit(limit + 1)
+	}
+
+	if cursor != nil {
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.Id)
+	}
+	return query.Order("created_at DESC, id DESC").Limit(limit + 1)
+}
+
+func mongoKeysetPage(filter bson.M, cursor *domain.Cursor, forward bool, limit int) (bson.M, *options.FindOptions) {
+	order := -1
+	op := "$lt"
+	if forward {
+		order = 1
+		op = "$gt"
+	}
+
+	if cursor != nil {
+		keyset := bson.M{"$or": bson.A{
+			bson.M{"created_at": bson.M{op: cursor.CreatedAt}},
+			bson.M{"created_at": cursor.CreatedAt, "_id": bson.M{op: cursor.Id}},
+		}}
+		filter = bson.M{"$and": bson.A{filter, keyset}}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: order}, {Key: "_id", Value: order}}).
+		SetLimit(int64(limit + 1))
+	return filter, opts
+}
+
+func reverseMessages(messages []*domain.Message) {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+}
+
+func reverseUsers(users []*domain.User) {
+	for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+		users[i], users[j] = users[j], users[i]
+	}
+}
+
+func reverseMentions(mentions []*domain.Mention) {
+	for i, j := 0, len(mentions)-1; i < j; i, j = i+1, j-1 {
+		mentions[i], mentions[j] = mentions[j], mentions[i]
+	}
+}
+
+func reverseStars(stars []*domain.Star) {
+	for i, j := 0, len(stars)-1; i < j; i, j = i+1, j-1 {
+		stars[i], stars[j] = stars[j], stars[i]
+	}
+}
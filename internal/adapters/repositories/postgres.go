@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+	"github.com/joho/godotenv"
+	"github.com/lib/pq"
+)
+
+var (
+	postgresMu  sync.Mutex
+	postgresDBs = make(map[string]*gorm.DB)
+)
+
+// newPostgresDB returns one shared pool per database URL, so every repository
+// of the same database uses the same connections.
+func newPostgresDB(urlEnv string) *gorm.DB {
+	postgresMu.Lock()
+	defer postgresMu.Unlock()
+
+	if db, ok := postgresDBs[urlEnv]; ok {
+		return db
+	}
+
+	err := godotenv.Load(".env")
+
+	if err != nil {
+		log.Fatal("Error loading file .env")
+	}
+
+	connStr := os.Getenv(urlEnv)
+
+	db, err := gorm.Open("postgres", connStr)
+	if err != nil {
+		panic(err)
+	}
+
+	postgresDBs[urlEnv] = db
+	return db
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type AttachmentPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentPostgresRepository() *AttachmentPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.Attachment{})
+	db.Model(&domain.Attachment{}).AddIndex("idx_attachments_message", "message_id")
+	db.Model(&domain.Attachment{}).AddIndex("idx_attachments_preview_status", "preview_status")
+	db.AutoMigrate(&domain.Thumbnail{})
+	db.Model(&domain.Thumbnail{}).AddUniqueIndex("idx_thumbnails_attachment_size", "attachment_id", "size")
+
+	return &AttachmentPostgresRepository{
+		db: db,
+	}
+}
+
+func (a *AttachmentPostgresRepository) AddAttachments(attachments []*domain.Attachment) error {
+	tx := a.db.Begin()
+	for _, attachment := range attachments {
+		req := tx.Create(attachment)
+		if req.RowsAffected == 0 {
+			tx.Rollback()
+			return errors.New(fmt.Sprintf("attachment not saved: %v", req.Error))
+		}
+	}
+	return tx.Commit().Error
+}
+
+func (a *AttachmentPostgresRepository) GetAttachment(id string) (*domain.Attachment, error) {
+	attachment := &domain.Attachment{}
+	req := a.db.First(&attachment, "id = ? ", id)
+	if req.RowsAffected == 0 {
+		return nil, errors.New(fmt.Sprintf("attachment not found: %v", req.Error))
+	}
+	return attachment, nil
+}
+
+func (a *AttachmentPostgresRepository) GetAttachments(messageIds []string) ([]*domain.Attachment, error) {
+	var attachments []*domain.Attachment
+	if len(messageIds) == 0 {
+		return attachments, nil
+	}
+
+	req := a.db.Where("message_id IN (?)", messageIds).Order("created_at").Find(&attachments)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("attachments not found: %v", req.Error))
+	}
+	return attachments, nil
+}
+
+func (a *AttachmentPostgresRepository) DeleteAttachments(messageIds []string) error {
+	req := a.db.Where("message_id IN (?)", messageIds).Delete(&domain.Attachment{})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to delete attachments: %v", req.Error))
+	}
+	return nil
+}
+
+// ClaimPendingPreviews leases pending previews to one instance, the same way
+// ClaimDueMessages does, so two instances never render the same thumbnails.
+func (a *AttachmentPostgresRepository) ClaimPendingPreviews(now time.Time, owner string, lease time.Duration, limit int) ([]*domain.Attachment, error) {
+	var attachments []*domain.Attachment
+	req := a.db.Raw(`UPDATE attachments SET claimed_by = ?, claimed_until = ?
+		WHERE id IN (
+			SELECT id FROM attachments
+			WHERE preview_status = ? AND (claimed_until IS NULL OR claimed_until < ?)
+			ORDER BY created_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		owner, now.Add(lease),
+		domain.PreviewPending, now,
+		limit,
+	).Scan(&attachments)
+	if req.Error != nil && !gorm.IsRecordNotFoundError(req.Error) {
+		return nil, errors.New(fmt.Sprintf("pending previews not claimed: %v", req.Error))
+	}
+	return attachments, nil
+}
+
+func (a *AttachmentPostgresRepository) SetPreviewStatus(id, status string) error {
+	req := a.db.Model(&domain.Attachment{}).Where("id = ?", id).Update("preview_status", status)
+	if req.RowsAffected == 0 {
+		return errors.New(fmt.Sprintf("attachment not found: %v", req.Error))
+	}
+	return nil
+}
+
+func (a *AttachmentPostgresRepository) AddThumbnails(thumbnails []*domain.Thumbnail) error {
+	tx := a.db.Begin()
+	for _, thumbnail := range thumbnails {
+		req := tx.Exec(`INSERT INTO thumbnails (attachment_id, size, width, height, mime_type, storage_key, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (attachment_id, size) DO UPDATE SET width = EXCLUDED.width, height = EXCLUDED.height,
+				mime_type = EXCLUDED.mime_type, storage_key = EXCLUDED.storage_key, created_at = EXCLUDED.created_at`,
+			thumbnail.AttachmentId, thumbnail.Size, thumbnail.Width, thumbnail.Height, thumbnail.MimeType, thumbnail.StorageKey, thumbnail.CreatedAt)
+		if req.Error != nil {
+			tx.Rollback()
+			return errors.New(fmt.Sprintf("thumbnail not saved: %v", req.Error))
+		}
+	}
+	return tx.Commit().Error
+}
+
+func (a *AttachmentPostgresRepository) GetThumbnails(attachmentIds []string) ([]*domain.Thumbnail, error) {
+	var thumbnails []*domain.Thumbnail
+	if len(attachmentIds) == 0 {
+		return thumbnails, nil
+	}
+
+	req := a.db.Where("attachment_id IN (?)", attachmentIds).Order("width").Find(&thumbnails)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("thumbnails not found: %v", req.Error))
+	}
+	return thumbnails, nil
+}
+
+func (a *AttachmentPostgresRepository) DeleteThumbnails(attachmentIds []string) error {
+	req := a.db.Where("attachment_id IN (?)", attachmentIds).Delete(&domain.Thumbnail{})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to delete thumbnails: %v", req.Error))
+	}
+	return nil
+}
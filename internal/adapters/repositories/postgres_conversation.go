@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type ConversationPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewConversationPostgresRepository() *ConversationPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.Conversation{}, &domain.ConversationMember{})
+	db.Model(&domain.Conversation{}).RemoveIndex("idx_conversations_direct_key")
+	// group conversations have an empty key, so only direct ones are unique
+	db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_conversations_direct_key_unique ON conversations (direct_key) WHERE type = ?", domain.ConversationDirect)
+	db.Model(&domain.ConversationMember{}).AddUniqueIndex("idx_conversation_members_conversation_user", "conversation_id", "user_id")
+
+	return &ConversationPostgresRepository{
+		db: db,
+	}
+}
+
+func (c *ConversationPostgresRepository) CreateConversation(conversation domain.Conversation, memberIds []string) error {
+	tx := c.db.Begin()
+
+	req := tx.Create(&conversation)
+	if isUniqueViolation(req.Error) {
+		tx.Rollback()
+		return domain.ErrDirectConversationExists
+	}
+	if req.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.New(fmt.Sprintf("conversation not saved: %v", req.Error))
+	}
+
+	for _, userId := range memberIds {
+		member := domain.ConversationMember{
+			ConversationId: conversation.Id,
+			UserId:         userId,
+			JoinedAt:       time.Now().UTC(),
+		}
+		req = tx.Create(&member)
+		if req.RowsAffected == 0 {
+			tx.Rollback()
+			return errors.New(fmt.Sprintf("conversation member not saved: %v", req.Error))
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+func (c *ConversationPostgresRepository) GetOneConversation(id string) (*domain.Conversation, error) {
+	conversation := &domain.Conversation{}
+	req := c.db.First(&conversation, "id = ? ", id)
+	if req.RowsAffected == 0 {
+		return nil, errors.New(fmt.Sprintf("conversation not found: %v", req.Error))
+	}
+	return conversation, nil
+}
+
+func (c *ConversationPostgresRepository) GetDirectConversation(directKey string) (*domain.Conversation, error) {
+	conversation := &domain.Conversation{}
+	req := c.db.First(&conversation, "type = ? AND direct_key = ? ", domain.ConversationDirect, directKey)
+	if gorm.IsRecordNotFoundError(req.Error) {
+		return nil, domain.ErrConversationNotFound
+	}
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("conversation not found: %v", req.Error))
+	}
+	return conversation, nil
+}
+
+func (c *ConversationPostgresRepository) GetUserConversations(userId string) ([]*domain.Conversation, error) {
+	var conversations []*domain.Conversation
+	req := c.db.
+		Joins("JOIN conversation_members ON conversation_members.conversation_id = conversations.id").
+		Where("conversation_members.user_id = ?", userId).
+		Order("conversations.updated_at DESC").
+		Find(&conversations)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("conversations not found: %v", req.Error))
+	}
+	return conversations, nil
+}
+
+func (c *ConversationPostgresRepository) AddMember(member domain.ConversationMember) error {
+	req := c.db.Create(&member)
+	if req.RowsAffected == 0 {
+		return errors.New(fmt.Sprintf("conversation member not saved: %v", req.Error))
+	}
+	return nil
+}
+
+func (c *ConversationPostgresRepository) RemoveMember(conversationId, userId string) error {
+	req := c.db.Where("conversation_id = ? AND user_id = ?", conversationId, userId).Delete(&domain.ConversationMember{})
+	if req.RowsAffected == 0 {
+		return errors.New("conversation member not found")
+	}
+	return nil
+}
+
+func (c *ConversationPostgresRepository) GetMembers(conversationId string) ([]*domain.ConversationMember, error) {
+	var members []*domain.ConversationMember
+	req := c.db.Where("conversation_id = ?", conversationId).Order("joined_at").Find(&members)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("conversation members not found: %v", req.Error))
+	}
+	return members, nil
+}
+
+// GetContactIds returns the distinct members of every conversation the user is in,
+// the user included.
+func (c *ConversationPostgresRepository) GetContactIds(userId string) ([]string, error) {
+	var ids []string
+	conversations := c.db.Model(&domain.ConversationMember{}).Select("conversation_id").Where("user_id = ?", userId).QueryExpr()
+	req := c.db.Model(&domain.ConversationMember{}).Where("conversation_id IN (?)", conversations).Pluck("DISTINCT user_id", &ids)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("contacts not found: %v", req.Error))
+	}
+	return ids, nil
+}
+
+func (c *ConversationPostgresRepository) IsMember(conversationId, userId string) (bool, error) {
+	var count int
+	req := c.db.Model(&domain.ConversationMember{}).Where("conversation_id = ? AND user_id = ?", conversationId, userId).Count(&count)
+	if req.Error != nil {
+		return false, errors.New(fmt.Sprintf("unable to check conversation member: %v", req.Error))
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type DraftPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewDraftPostgresRepository() *DraftPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.Draft{})
+	db.Model(&domain.Draft{}).AddUniqueIndex("idx_drafts_user_conversation", "user_id", "conversation_id")
+
+	return &DraftPostgresRepository{
+		db: db,
+	}
+}
+
+func (d *DraftPostgresRepository) SetDraft(draft domain.Draft) error {
+	req := d.db.Exec(`INSERT INTO drafts (conversation_id, user_id, body, parent_id, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, conversation_id) DO UPDATE SET body = EXCLUDED.body, parent_id = EXCLUDED.parent_id, updated_at = EXCLUDED.updated_at`,
+		draft.ConversationId, draft.UserId, draft.Body, draft.ParentId, draft.UpdatedAt)
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("draft not saved: %v", req.Error))
+	}
+	return nil
+}
+
+func (d *DraftPostgresRepository) GetDraft(userId, conversationId string) (*domain.Draft, error) {
+	draft := &domain.Draft{}
+	req := d.db.First(&draft, "user_id = ? AND conversation_id = ?", userId, conversationId)
+	if req.RowsAffected == 0 {
+		return nil, errors.New("draft not found")
+	}
+	return draft, nil
+}
+
+func (d *DraftPostgresRepository) GetDrafts(userId string) ([]*domain.Draft, error) {
+	var drafts []*domain.Draft
+	req := d.db.Where("user_id = ?", userId).Order("updated_at DESC").Find(&drafts)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("drafts not found: %v", req.Error))
+	}
+	return drafts, nil
+}
+
+func (d *DraftPostgresRepository) DeleteDraft(userId, conversationId string) (bool, error) {
+	req := d.db.Where("user_id = ? AND conversation_id = ?", userId, conversationId).Delete(&domain.Draft{})
+	if req.Error != nil {
+		return false, errors.New(fmt.Sprintf("unable to delete draft: %v", req.Error))
+	}
+	return req.RowsAffected > 0, nil
+}
+
+// ClearDraft deletes the draft only while it is for the same thread as parentId.
+func (d *DraftPostgresRepository) ClearDraft(userId, conversationId, parentId string) (bool, error) {
+	req := d.db.Where("user_id = ? AND conversation_id = ? AND parent_id = ?", userId, conversationId, parentId).Delete(&domain.Draft{})
+	if req.Error != nil {
+		return false, errors.New(fmt.Sprintf("unable to delete draft: %v", req.Error))
+	}
+	return req.RowsAffected > 0, nil
+}
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type IncomingWebhookPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewIncomingWebhookPostgresRepository() *IncomingWebhookPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.IncomingWebhook{})
+	db.Model(&domain.IncomingWebhook{}).AddUniqueIndex("idx_incoming_webhooks_token_hash", "token_hash")
+	db.Model(&domain.IncomingWebhook{}).AddIndex("idx_incoming_webhooks_conversation", "conversation_id")
+
+	return &IncomingWebhookPostgresRepository{
+		db: db,
+	}
+}
+
+func (i *IncomingWebhookPostgresRepository) AddIncomingWebhook(hook domain.IncomingWebhook) error {
+	req := i.db.Create(&hook)
+	if req.RowsAffected == 0 {
+		return errors.New(fmt.Sprintf("incoming webhook not saved: %v", req.Error))
+	}
+	return nil
+}
+
+func (i *IncomingWebhookPostgresRepository) GetIncomingWebhook(id string) (*domain.IncomingWebhook, error) {
+	hook := &domain.IncomingWebhook{}
+	req := i.db.First(&hook, "id = ?", id)
+	if req.RowsAffected == 0 {
+		return nil, errors.New(fmt.Sprintf("incoming webhook not found: %v", req.Error))
+	}
+	return hook, nil
+}
+
+func (i *IncomingWebhookPostgresRepository) GetIncomingWebhookByToken(tokenHash string) (*domain.IncomingWebhook, error) {
+	hook := &domain.IncomingWebhook{}
+	req := i.db.First(&hook, "token_hash = ?", tokenHash)
+	if req.RowsAffected == 0 {
+		return nil, errors.New("incoming webhook not found")
+	}
+	return hook, nil
+}
+
+func (i *IncomingWebhookPostgresRepository) GetIncomingWebhooks(conversationId string) ([]*domain.IncomingWebhook, error) {
+	var hooks []*domain.IncomingWebhook
+	req := i.db.Where("conversation_id = ?", conversationId).Order("created_at").Find(&hooks)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("incoming webhooks not found: %v", req.Error))
+	}
+	return hooks, nil
+}
+
+func (i *IncomingWebhookPostgresRepository) RotateIncomingWebhook(id, tokenHash string, rotatedAt time.Time) error {
+	req := i.db.Model(&domain.IncomingWebhook{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"token_hash": tokenHash, "rotated_at": rotatedAt})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("incoming webhook not rotated: %v", req.Error))
+	}
+	if req.RowsAffected == 0 {
+		return errors.New("incoming webhook not found")
+	}
+	return nil
+}
+
+func (i *IncomingWebhookPostgresRepository) DeleteIncomingWebhook(id string) error {
+	req := i.db.Where("id = ?", id).Delete(&domain.IncomingWebhook{})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to delete incoming webhook: %v", req.Error))
+	}
+	if req.RowsAffected == 0 {
+		return errors.New("incoming webhook not found")
+	}
+	return nil
+}
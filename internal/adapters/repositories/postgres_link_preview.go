@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type LinkPreviewPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewLinkPreviewPostgresRepository() *LinkPreviewPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.LinkPreview{})
+	db.Model(&domain.LinkPreview{}).AddUniqueIndex("idx_link_previews_message_url", "message_id", "url")
+
+	return &LinkPreviewPostgresRepository{
+		db: db,
+	}
+}
+
+func (l *LinkPreviewPostgresRepository) SetLinkPreviews(messageId string, previews []*domain.LinkPreview) error {
+	tx := l.db.Begin()
+	if err := tx.Where("message_id = ?", messageId).Delete(&domain.LinkPreview{}).Error; err != nil {
+		tx.Rollback()
+		return errors.New(fmt.Sprintf("link previews not saved: %v", err))
+	}
+
+	for _, preview := range previews {
+		if err := tx.Create(preview).Error; err != nil {
+			tx.Rollback()
+			return errors.New(fmt.Sprintf("link previews not saved: %v", err))
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New(fmt.Sprintf("link previews not saved: %v", err))
+	}
+	return nil
+}
+
+func (l *LinkPreviewPostgresRepository) GetLinkPreviews(messageIds []string) ([]*domain.LinkPreview, error) {
+	var previews []*domain.LinkPreview
+	if len(messageIds) == 0 {
+		return previews, nil
+	}
+
+	req := l.db.Where("message_id IN (?)", messageIds).Order("position").Find(&previews)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("link previews not found: %v", req.Error))
+	}
+	return previews, nil
+}
+
+func (l *LinkPreviewPostgresRepository) DeleteLinkPreviews(messageIds []string) error {
+	req := l.db.Where("message_id IN (?)", messageIds).Delete(&domain.LinkPreview{})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to delete link previews: %v", req.Error))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type MentionPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewMentionPostgresRepository() *MentionPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.Mention{})
+	db.Model(&domain.Mention{}).AddUniqueIndex("idx_mentions_message_user", "message_id", "user_id")
+	db.Model(&domain.Mention{}).AddIndex("idx_mentions_user_created", "user_id", "created_at", "id")
+
+	return &MentionPostgresRepository{
+		db: db,
+	}
+}
+
+func (m *MentionPostgresRepository) SetMentions(messageId string, mentions []*domain.Mention) error {
+	tx := m.db.Begin()
+	if req := tx.Where("message_id = ?", messageId).Delete(&domain.Mention{}); req.Error != nil {
+		tx.Rollback()
+		return errors.New(fmt.Sprintf("mentions not saved: %v", req.Error))
+	}
+
+	for _, mention := range mentions {
+		req := tx.Create(mention)
+		if req.RowsAffected == 0 {
+			tx.Rollback()
+			return errors.New(fmt.Sprintf("mention not saved: %v", req.Error))
+		}
+	}
+	return tx.Commit().Error
+}
+
+func (m *MentionPostgresRepository) GetMentions(messageIds []string) ([]*domain.Mention, error) {
+	var mentions []*domain.Mention
+	if len(messageIds) == 0 {
+		return mentions, nil
+	}
+
+	req := m.db.Where("message_id IN (?)", messageIds).Order("\"offset\"").Find(&mentions)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("mentions not found: %v", req.Error))
+	}
+	return mentions, nil
+}
+
+func (m *MentionPostgresRepository) GetUserMentions(userId string, query domain.PageQuery) (*domain.MentionPage, error) {
+	cursor, forward, err := query.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	var mentions []*domain.Mention
+	req := keysetPage(m.db.Where("user_id = ?", userId), cursor, forward, query.Limit).Find(&mentions)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("mentions not found: %v", req.Error))
+	}
+
+	page := &domain.MentionPage{Mentions: mentions}
+	if len(mentions) > query.Limit {
+		page.Mentions = mentions[:query.Limit]
+		last := page.Mentions[query.Limit-1]
+		page.NextCursor = domain.EncodeCursor(last.CreatedAt, last.Id)
+	}
+	if forward {
+		reverseMentions(page.Mentions)
+	}
+	return page, nil
+}
+
+func (m *MentionPostgresRepository) DeleteMentions(messageIds []string) error {
+	req := m.db.Where("message_id IN (?)", messageIds).Delete(&domain.Mention{})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to delete mentions: %v", req.Error))
+	}
+	return nil
+}
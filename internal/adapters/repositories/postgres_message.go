@@ -3,12 +3,10 @@ package repositories
 import (
 	"errors"
 	"fmt"
-	"log"
-	"os"
+	"strings"
+	"time"
 
 	"github.com/jinzhu/gorm"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
 	"messenger/internal/core/domain"
 )
 
@@ -16,53 +14,114 @@ type MessangerPostgresRepository struct {
 	db *gorm.DB
 }
 
-func NewMessangerPostgresRepository() *MessangerPostgresRepository {
-	err := godotenv.Load(".env")
-
-	if err != nil {
-		log.Fatal("Error loading file .env")
-	}
-
-	connStr := os.Getenv("POSTGRES_MESSANGER_URL")
+type messageSearchRow struct {
+	domain.Message
+	Rank    float64
+	Snippet string
+}
 
-	db, err := gorm.Open("postgres", connStr)
-	if err != nil {
-		panic(err)
-	}
+func NewMessangerPostgresRepository() *MessangerPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
 	db.AutoMigrate(&domain.Message{})
+	db.Model(&domain.Message{}).AddIndex("idx_messages_conversation_created", "conversation_id", "created_at", "id")
+	db.Model(&domain.Message{}).AddIndex("idx_messages_thread_root_created", "thread_root_id", "created_at", "id")
+	db.Model(&domain.Message{}).AddIndex("idx_messages_expires_at", "expires_at")
+	db.Exec("ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (to_tsvector('simple', coalesce(body, ''))) STORED")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_messages_search_vector ON messages USING GIN (search_vector)")
 
+	// tombstones are managed by hand, so keep gorm from treating deleted_at as its own soft delete
 	return &MessangerPostgresRepository{
-		db: db,
+		db: db.Unscoped(),
 	}
 }
 
+// CreateMessage saves a reply and bumps its thread root in one transaction,
+// so reply_count never drifts from the replies actually stored.
 func (m *MessangerPostgresRepository) CreateMessage(message domain.Message) error {
-	req := m.db.Create(&message)
+	tx := m.db.Begin()
+	req := tx.Create(&message)
 	if req.RowsAffected == 0 {
+		tx.Rollback()
 		return errors.New(fmt.Sprintf("messages not saved: %v", req.Error))
 	}
+
+	if message.ThreadRootId != "" {
+		if err := addThreadReply(tx, message.ThreadRootId, message.CreatedAt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New(fmt.Sprintf("messages not saved: %v", err))
+	}
 	return nil
 }
 
 func (m *MessangerPostgresRepository) GetOneMessage(id string) (*domain.Message, error) {
 	message := &domain.Message{}
-	req := m.db.First(&message, "id = ? ", id)
+	req := notExpired(m.db).First(&message, "id = ? ", id)
 	if req.RowsAffected == 0 {
 		return nil, errors.New(fmt.Sprintf("message not found: %v", req.Error))
 	}
 	return message, nil
 }
 
-func (m *MessangerPostgresRepository) GetAllMessages() ([]*domain.Message, error) {
+func (m *MessangerPostgresRepository) GetMessages(ids []string) ([]*domain.Message, error) {
 	var messages []*domain.Message
-	req := m.db.Find(&messages)
-	if req.RowsAffected == 0 {
+	if len(ids) == 0 {
+		return messages, nil
+	}
+
+	req := notExpired(m.db).Where("id IN (?)", ids).Find(&messages)
+	if req.Error != nil {
 		return nil, errors.New(fmt.Sprintf("messages not found: %v", req.Error))
 	}
 	return messages, nil
 }
 
-func (m *MessangerPostgresRepository) UpdateMessage(id, body, user_id string) (*domain.Message, error) {
+func (m *MessangerPostgresRepository) GetAllMessages(filter domain.MessageFilter) (*domain.MessagePage, error) {
+	cursor, forward, err := filter.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	query := notExpired(m.db).Where("conversation_id = ?", filter.ConversationId)
+	if filter.ThreadRootId != "" {
+		query = query.Where("thread_root_id = ?", filter.ThreadRootId)
+	} else {
+		query = query.Where("thread_root_id = '' OR thread_root_id IS NULL")
+	}
+	if filter.UserId != "" {
+		query = query.Where("user_id = ?", filter.UserId)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	query = keysetPage(query, cursor, forward, filter.Limit)
+
+	var messages []*domain.Message
+	req := query.Find(&messages)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("messages not found: %v", req.Error))
+	}
+
+	page := &domain.MessagePage{Messages: messages}
+	if len(messages) > filter.Limit {
+		page.Messages = messages[:filter.Limit]
+		last := page.Messages[filter.Limit-1]
+		page.NextCursor = domain.EncodeCursor(last.CreatedAt, last.Id)
+	}
+	if forward {
+		reverseMessages(page.Messages)
+	}
+	return page, nil
+}
+
+func (m *MessangerPostgresRepository) UpdateMessage(id, body, user_id string, editedAt time.Time) (*domain.Message, error) {
 	var message domain.Message
 
 	req := m.db.First(&message, "id = ? ", id)
@@ -70,6 +129,9 @@ func (m *MessangerPostgresRepository) UpdateMessage(id, body, user_id string) (*
 		return nil, errors.New("message not found")
 	}
 	message.Body = body
+	message.Edited = true
+	message.EditedAt = &editedAt
+	message.UpdatedAt = editedAt
 
 	req = m.db.Model(&message).Where("id = ? AND user_id = ?", id, user_id).Update(message)
 	if req.RowsAffected == 0 {
@@ -80,12 +142,241 @@ func (m *MessangerPostgresRepository) UpdateMessage(id, body, user_id string) (*
 
 }
 
-func (m *MessangerPostgresRepository) DeleteMessage(id, user_id string) error {
-	message := &domain.Message{}
-	req := m.db.Where("id = ? AND user_id = ?", id, user_id).Delete(&message)
+// addThreadReply leaves updated_at alone, since a new reply does not edit the root.
+func addThreadReply(tx *gorm.DB, rootId string, repliedAt time.Time) error {
+	req := tx.Model(&domain.Message{}).Where("id = ?", rootId).UpdateColumns(map[string]interface{}{
+		"reply_count":   gorm.Expr("reply_count + 1"),
+		"last_reply_at": gorm.Expr("GREATEST(COALESCE(last_reply_at, ?), ?)", repliedAt, repliedAt),
+	})
+	if req.RowsAffected == 0 {
+		return errors.New(fmt.Sprintf("thread root not updated: %v", req.Error))
+	}
+	return nil
+}
+
+// CountMessagesSince counts, in one query, the messages of each conversation
+// posted by others after the time given for it.
+func (m *MessangerPostgresRepository) CountMessagesSince(excludeUserId string, since map[string]time.Time) (map[string]int, error) {
+	counts := make(map[string]int, len(since))
+	if len(since) == 0 {
+		return counts, nil
+	}
+
+	conditions := make([]string, 0, len(since))
+	args := make([]interface{}, 0, 2*len(since))
+	for conversationId, at := range since {
+		conditions = append(conditions, "(conversation_id = ? AND created_at > ?)")
+		args = append(args, conversationId, at)
+	}
+
+	var rows []*domain.UnreadCount
+	req := notExpired(m.db.Model(&domain.Message{})).
+		Select("conversation_id, COUNT(*) AS unread").
+		Where("user_id <> ? AND deleted_at IS NULL", excludeUserId).
+		Where(strings.Join(conditions, " OR "), args...).
+		Group("conversation_id").
+		Scan(&rows)
+	if req.Error != nil && !gorm.IsRecordNotFoundError(req.Error) {
+		return nil, errors.New(fmt.Sprintf("unable to count messages: %v", req.Error))
+	}
+
+	for _, row := range rows {
+		counts[row.ConversationId] = row.Unread
+	}
+	return counts, nil
+}
+
+func (m *MessangerPostgresRepository) DeleteMessage(id, user_id string, deletedAt time.Time) error {
+	req := m.db.Model(&domain.Message{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NULL", id, user_id).
+		Updates(map[string]interface{}{"deleted_at": deletedAt, "deleted_by": user_id})
 	if req.RowsAffected == 0 {
 		return errors.New("message not found")
 	}
 
 	return nil
 }
+
+func (m *MessangerPostgresRepository) RestoreMessage(id, user_id string, deletedSince time.Time) error {
+	req := m.db.Model(&domain.Message{}).
+		Where("id = ? AND deleted_by = ? AND deleted_at >= ?", id, user_id, deletedSince).
+		Updates(map[string]interface{}{"deleted_at": nil, "deleted_by": ""})
+	if req.RowsAffected == 0 {
+		return errors.New("no deleted message to restore")
+	}
+
+	return nil
+}
+
+func (m *MessangerPostgresRepository) PurgeDeletedMessages(deletedBefore time.Time) ([]string, error) {
+	var messages []*domain.Message
+
+	// a thread root stays as a tombstone until its replies are purged, so threads never lose their root
+	req := m.db.
+		Where("deleted_at < ? AND NOT EXISTS (SELECT 1 FROM messages replies WHERE replies.thread_root_id = messages.id)", deletedBefore).
+		Find(&messages)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("deleted messages not found: %v", req.Error))
+	}
+
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(messages))
+	replies := make(map[string]int)
+	for _, message := range messages {
+		ids = append(ids, message.Id)
+		if message.ThreadRootId != "" {
+			replies[message.ThreadRootId]++
+		}
+	}
+
+	tx := m.db.Begin()
+	if req := tx.Where("id IN (?)", ids).Delete(&domain.Message{}); req.Error != nil {
+		tx.Rollback()
+		return nil, errors.New(fmt.Sprintf("unable to purge messages: %v", req.Error))
+	}
+
+	for rootId, count := range replies {
+		req := tx.Model(&domain.Message{}).Where("id = ?", rootId).
+			UpdateColumn("reply_count", gorm.Expr("GREATEST(reply_count - ?, 0)", count))
+		if req.Error != nil {
+			tx.Rollback()
+			return nil, errors.New(fmt.Sprintf("unable to purge messages: %v", req.Error))
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (m *MessangerPostgresRepository) SearchMessages(query domain.SearchQuery) (*domain.SearchPage, error) {
+	page := &domain.SearchPage{Results: []*domain.SearchResult{}}
+	if len(query.ConversationIds) == 0 {
+		return page, nil
+	}
+
+	var rows []*messageSearchRow
+	req := m.db.Raw(`
+		SELECT messages.*,
+			ts_rank(messages.search_vector, q) AS rank,
+			ts_headline('simple', replace(replace(replace(messages.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), q,
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, FragmentDelimiter=" ... "') AS snippet
+		FROM messages, websearch_to_tsquery('simple', ?) q
+		WHERE messages.conversation_id IN (?) AND messages.deleted_at IS NULL AND messages.search_vector @@ q
+			AND (messages.expires_at IS NULL OR messages.expires_at > now())
+		ORDER BY rank DESC, messages.created_at DESC, messages.id DESC
+		LIMIT ? OFFSET ?`,
+		query.Query, query.ConversationIds, query.Limit+1, query.Offset).Scan(&rows)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("messages not found: %v", req.Error))
+	}
+
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+		page.NextCursor = domain.EncodeOffsetCursor(query.Offset + query.Limit)
+	}
+
+	for _, row := range rows {
+		message := row.Message
+		page.Results = append(page.Results, &domain.SearchResult{
+			Message: &message,
+			Rank:    row.Rank,
+			Snippet: row.Snippet,
+		})
+	}
+	return page, nil
+}
+
+// StartReadExpiry starts the countdown of "expire after read" messages once another
+// member has read them. LEAST keeps an earlier expiry from being pushed back.
+func (m *MessangerPostgresRepository) StartReadExpiry(conversationId, readerId string, readUntil, readAt time.Time) error {
+	req := m.db.Exec(`UPDATE messages
+		SET expires_at = LEAST(COALESCE(expires_at, 'infinity'), ? + expire_after_read * interval '1 second')
+		WHERE conversation_id = ? AND user_id <> ? AND created_at <= ? AND expire_after_read > 0 AND deleted_at IS NULL`,
+		readAt, conversationId, readerId, readUntil)
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to start message expiry: %v", req.Error))
+	}
+	return nil
+}
+
+func (m *MessangerPostgresRepository) ExpireMessages(now time.Time) ([]*domain.Message, error) {
+	var messages []*domain.Message
+	req := m.db.Where("expires_at <= ?", now).Find(&messages)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("expired messages not found: %v", req.Error))
+	}
+
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.Id)
+	}
+
+	var roots []string
+	req = m.db.Model(&domain.Message{}).Where("thread_root_id IN (?)", ids).Pluck("DISTINCT thread_root_id", &roots)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("unable to expire messages: %v", req.Error))
+	}
+
+	withReplies := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		withReplies[root] = true
+	}
+
+	var deleted, emptied []string
+	replies := make(map[string]int)
+	for _, message := range messages {
+		if withReplies[message.Id] {
+			emptied = append(emptied, message.Id)
+			continue
+		}
+		deleted = append(deleted, message.Id)
+		if message.ThreadRootId != "" {
+			replies[message.ThreadRootId]++
+		}
+	}
+
+	tx := m.db.Begin()
+	if len(deleted) > 0 {
+		if req := tx.Where("id IN (?)", deleted).Delete(&domain.Message{}); req.Error != nil {
+			tx.Rollback()
+			return nil, errors.New(fmt.Sprintf("unable to expire messages: %v", req.Error))
+		}
+	}
+
+	// a thread root with replies keeps its place as an emptied tombstone
+	if len(emptied) > 0 {
+		req := tx.Exec(`UPDATE messages SET body = '', deleted_at = expires_at, deleted_by = '', expires_at = NULL, expire_after_read = 0
+			WHERE id IN (?)`, emptied)
+		if req.Error != nil {
+			tx.Rollback()
+			return nil, errors.New(fmt.Sprintf("unable to expire messages: %v", req.Error))
+		}
+	}
+
+	for rootId, count := range replies {
+		req := tx.Model(&domain.Message{}).Where("id = ?", rootId).
+			UpdateColumn("reply_count", gorm.Expr("GREATEST(reply_count - ?, 0)", count))
+		if req.Error != nil {
+			tx.Rollback()
+			return nil, errors.New(fmt.Sprintf("unable to expire messages: %v", req.Error))
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func notExpired(query *gorm.DB) *gorm.DB {
+	return query.Where("expires_at IS NULL OR expires_at > ?", time.Now().UTC())
+}
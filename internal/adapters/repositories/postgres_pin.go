@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type PinPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewPinPostgresRepository() *PinPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.Pin{})
+	db.Model(&domain.Pin{}).AddUniqueIndex("idx_pins_conversation_message", "conversation_id", "message_id")
+
+	return &PinPostgresRepository{
+		db: db,
+	}
+}
+
+func (p *PinPostgresRepository) AddPin(pin domain.Pin) error {
+	req := p.db.Exec(`INSERT INTO pins (conversation_id, message_id, pinned_by, pinned_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (conversation_id, message_id) DO NOTHING`,
+		pin.ConversationId, pin.MessageId, pin.PinnedBy, pin.PinnedAt)
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("pin not saved: %v", req.Error))
+	}
+	if req.RowsAffected == 0 {
+		return errors.New("message already pinned")
+	}
+	return nil
+}
+
+func (p *PinPostgresRepository) RemovePin(conversationId, messageId string) error {
+	req := p.db.Where("conversation_id = ? AND message_id = ?", conversationId, messageId).Delete(&domain.Pin{})
+	if req.RowsAffected == 0 {
+		return errors.New("pin not found")
+	}
+	return nil
+}
+
+func (p *PinPostgresRepository) GetPins(conversationId string) ([]*domain.Pin, error) {
+	var pins []*domain.Pin
+	req := p.db.Where("conversation_id = ?", conversationId).Order("pinned_at DESC").Find(&pins)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("pins not found: %v", req.Error))
+	}
+	return pins, nil
+}
+
+func (p *PinPostgresRepository) DeletePins(messageIds []string) error {
+	req := p.db.Where("message_id IN (?)", messageIds).Delete(&domain.Pin{})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to delete pins: %v", req.Error))
+	}
+	return nil
+}
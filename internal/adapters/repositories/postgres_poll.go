@@ -0,0 +1,222 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type pollOptionRow struct {
+	MessageId string
+	OptionId  string
+	Text      string
+	Position  int
+}
+
+func (pollOptionRow) TableName() string {
+	return "poll_options"
+}
+
+type pollVoteOptionRow struct {
+	MessageId string
+	UserId    string
+	OptionId  string
+}
+
+func (pollVoteOptionRow) TableName() string {
+	return "poll_vote_options"
+}
+
+type PollPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewPollPostgresRepository() *PollPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.Poll{}, &pollOptionRow{}, &domain.PollVote{}, &pollVoteOptionRow{})
+	db.Model(&pollOptionRow{}).AddUniqueIndex("idx_poll_options_message_option", "message_id", "option_id")
+	db.Model(&domain.PollVote{}).AddUniqueIndex("idx_poll_votes_message_user", "message_id", "user_id")
+	db.Model(&pollVoteOptionRow{}).AddUniqueIndex("idx_poll_vote_options_message_user_option", "message_id", "user_id", "option_id")
+
+	return &PollPostgresRepository{
+		db: db,
+	}
+}
+
+func (p *PollPostgresRepository) CreatePoll(poll domain.Poll) error {
+	tx := p.db.Begin()
+	if err := tx.Create(&poll).Error; err != nil {
+		tx.Rollback()
+		return errors.New(fmt.Sprintf("poll not saved: %v", err))
+	}
+
+	for i, option := range poll.Options {
+		row := pollOptionRow{MessageId: poll.MessageId, OptionId: option.Id, Text: option.Text, Position: i}
+		if err := tx.Create(&row).Error; err != nil {
+			tx.Rollback()
+			return errors.New(fmt.Sprintf("poll not saved: %v", err))
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New(fmt.Sprintf("poll not saved: %v", err))
+	}
+	return nil
+}
+
+func (p *PollPostgresRepository) GetPolls(messageIds []string) ([]*domain.Poll, error) {
+	var polls []*domain.Poll
+	if len(messageIds) == 0 {
+		return polls, nil
+	}
+
+	if err := p.db.Where("message_id IN (?)", messageIds).Find(&polls).Error; err != nil {
+		return nil, errors.New(fmt.Sprintf("polls not found: %v", err))
+	}
+
+	var rows []*pollOptionRow
+	if err := p.db.Where("message_id IN (?)", messageIds).Order("position").Find(&rows).Error; err != nil {
+		return nil, errors.New(fmt.Sprintf("polls not found: %v", err))
+	}
+
+	options := make(map[string][]domain.PollOption)
+	for _, row := range rows {
+		options[row.MessageId] = append(options[row.MessageId], domain.PollOption{Id: row.OptionId, Text: row.Text})
+	}
+	for _, poll := range polls {
+		poll.Options = options[poll.MessageId]
+	}
+	return polls, nil
+}
+
+func (p *PollPostgresRepository) ClosePoll(messageId string, closedAt time.Time) error {
+	req := p.db.Model(&domain.Poll{}).Where("message_id = ? AND closed_at IS NULL", messageId).Update("closed_at", closedAt)
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("poll not closed: %v", req.Error))
+	}
+	if req.RowsAffected == 0 {
+		return errors.New("poll is already closed")
+	}
+	return nil
+}
+
+// lockOpenPoll share-locks the poll row for the rest of tx if the poll is still
+// open at now. ClosePoll has to wait for the lock, so no ballot lands after it.
+func lockOpenPoll(tx *gorm.DB, messageId string, now time.Time) error {
+	poll := &domain.Poll{}
+	req := tx.Set("gorm:query_option", "FOR SHARE").
+		Where("message_id = ? AND closed_at IS NULL AND (closes_at IS NULL OR closes_at > ?)", messageId, now).
+		First(poll)
+	if gorm.IsRecordNotFoundError(req.Error) {
+		return domain.ErrPollClosed
+	}
+	return req.Error
+}
+
+// CastVote replaces the user's ballot. The upsert takes a row lock on the ballot,
+// so concurrent votes by the same user are applied one after the other.
+func (p *PollPostgresRepository) CastVote(vote domain.PollVote) error {
+	tx := p.db.Begin()
+	if err := lockOpenPoll(tx, vote.MessageId, vote.UpdatedAt); err != nil {
+		tx.Rollback()
+		if err == domain.ErrPollClosed {
+			return err
+		}
+		return errors.New(fmt.Sprintf("vote not saved: %v", err))
+	}
+
+	req := tx.Exec(`INSERT INTO poll_votes (message_id, user_id, created_at, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (message_id, user_id) DO UPDATE SET updated_at = EXCLUDED.updated_at`,
+		vote.MessageId, vote.UserId, vote.CreatedAt, vote.UpdatedAt)
+	err := req.Error
+	if err == nil && req.RowsAffected == 0 {
+		err = errors.New("ballot not written")
+	}
+	if err == nil {
+		err = tx.Where("message_id = ? AND user_id = ?", vote.MessageId, vote.UserId).Delete(&pollVoteOptionRow{}).Error
+	}
+	for _, optionId := range vote.OptionIds {
+		if err != nil {
+			break
+		}
+		err = tx.Create(&pollVoteOptionRow{MessageId: vote.MessageId, UserId: vote.UserId, OptionId: optionId}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return errors.New(fmt.Sprintf("vote not saved: %v", err))
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New(fmt.Sprintf("vote not saved: %v", err))
+	}
+	return nil
+}
+
+func (p *PollPostgresRepository) RemoveVote(messageId, userId string, now time.Time) error {
+	tx := p.db.Begin()
+	if err := lockOpenPoll(tx, messageId, now); err != nil {
+		tx.Rollback()
+		if err == domain.ErrPollClosed {
+			return err
+		}
+		return errors.New(fmt.Sprintf("unable to delete vote: %v", err))
+	}
+
+	req := tx.Where("message_id = ? AND user_id = ?", messageId, userId).Delete(&domain.PollVote{})
+	if req.Error != nil {
+		tx.Rollback()
+		return errors.New(fmt.Sprintf("unable to delete vote: %v", req.Error))
+	}
+	if req.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.New("vote not found")
+	}
+
+	if err := tx.Where("message_id = ? AND user_id = ?", messageId, userId).Delete(&pollVoteOptionRow{}).Error; err != nil {
+		tx.Rollback()
+		return errors.New(fmt.Sprintf("unable to delete vote: %v", err))
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New(fmt.Sprintf("unable to delete vote: %v", err))
+	}
+	return nil
+}
+
+func (p *PollPostgresRepository) GetVotes(messageIds []string) ([]*domain.PollVote, error) {
+	var votes []*domain.PollVote
+	if len(messageIds) == 0 {
+		return votes, nil
+	}
+
+	if err := p.db.Where("message_id IN (?)", messageIds).Order("created_at").Find(&votes).Error; err != nil {
+		return nil, errors.New(fmt.Sprintf("votes not found: %v", err))
+	}
+
+	var rows []*pollVoteOptionRow
+	if err := p.db.Where("message_id IN (?)", messageIds).Find(&rows).Error; err != nil {
+		return nil, errors.New(fmt.Sprintf("votes not found: %v", err))
+	}
+
+	choices := make(map[string][]string)
+	for _, row := range rows {
+		key := row.MessageId + "/" + row.UserId
+		choices[key] = append(choices[key], row.OptionId)
+	}
+	for _, vote := range votes {
+		vote.OptionIds = choices[vote.MessageId+"/"+vote.UserId]
+	}
+	return votes, nil
+}
+
+func (p *PollPostgresRepository) DeletePolls(messageIds []string) error {
+	for _, model := range []interface{}{&pollVoteOptionRow{}, &domain.PollVote{}, &pollOptionRow{}, &domain.Poll{}} {
+		if err := p.db.Where("message_id IN (?)", messageIds).Delete(model).Error; err != nil {
+			return errors.New(fmt.Sprintf("unable to delete polls: %v", err))
+		}
+	}
+	return nil
+}
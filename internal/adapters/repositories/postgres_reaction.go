@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type ReactionPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewReactionPostgresRepository() *ReactionPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.Reaction{})
+	db.Model(&domain.Reaction{}).AddUniqueIndex("idx_reactions_message_user_emoji", "message_id", "user_id", "emoji")
+
+	return &ReactionPostgresRepository{
+		db: db,
+	}
+}
+
+func (r *ReactionPostgresRepository) AddReaction(reaction domain.Reaction) error {
+	req := r.db.Create(&reaction)
+	if isUniqueViolation(req.Error) {
+		return errors.New("user already reacted with this emoji")
+	}
+	if req.RowsAffected == 0 {
+		return errors.New(fmt.Sprintf("reaction not saved: %v", req.Error))
+	}
+	return nil
+}
+
+func (r *ReactionPostgresRepository) RemoveReaction(messageId, userId, emoji string) error {
+	req := r.db.Where("message_id = ? AND user_id = ? AND emoji = ?", messageId, userId, emoji).Delete(&domain.Reaction{})
+	if req.RowsAffected == 0 {
+		return errors.New("reaction not found")
+	}
+	return nil
+}
+
+// GetReactionSummaries counts the reactions per message and emoji, in the order
+// each emoji was first used.
+func (r *ReactionPostgresRepository) GetReactionSummaries(messageIds []string, userId string) ([]*domain.ReactionSummary, error) {
+	var summaries []*domain.ReactionSummary
+	if len(messageIds) == 0 {
+		return summaries, nil
+	}
+
+	req := r.db.Raw(`SELECT message_id, emoji, COUNT(*) AS count, BOOL_OR(user_id = ?) AS reacted
+		FROM reactions
+		WHERE message_id IN (?)
+		GROUP BY message_id, emoji
+		ORDER BY MIN(created_at)`, userId, messageIds).Scan(&summaries)
+	if req.Error != nil && !gorm.IsRecordNotFoundError(req.Error) {
+		return nil, errors.New(fmt.Sprintf("reactions not found: %v", req.Error))
+	}
+	return summaries, nil
+}
+
+func (r *ReactionPostgresRepository) DeleteReactions(messageIds []string) error {
+	req := r.db.Where("message_id IN (?)", messageIds).Delete(&domain.Reaction{})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to delete reactions: %v", req.Error))
+	}
+	return nil
+}
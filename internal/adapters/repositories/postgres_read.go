@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type ReadMarkerPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewReadMarkerPostgresRepository() *ReadMarkerPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.ReadMarker{})
+	db.Model(&domain.ReadMarker{}).AddUniqueIndex("idx_read_markers_conversation_user", "conversation_id", "user_id")
+
+	return &ReadMarkerPostgresRepository{
+		db: db,
+	}
+}
+
+func (r *ReadMarkerPostgresRepository) SetReadMarker(marker domain.ReadMarker) (bool, error) {
+	req := r.db.Exec(`
+		INSERT INTO read_markers (conversation_id, user_id, last_read_message_id, last_read_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (conversation_id, user_id) DO UPDATE SET
+			last_read_message_id = EXCLUDED.last_read_message_id,
+			last_read_at = EXCLUDED.last_read_at,
+			updated_at = EXCLUDED.updated_at
+		WHERE read_markers.last_read_at < EXCLUDED.last_read_at`,
+		marker.ConversationId, marker.UserId, marker.LastReadMessageId, marker.LastReadAt, marker.UpdatedAt)
+	if req.Error != nil {
+		return false, errors.New(fmt.Sprintf("read marker not saved: %v", req.Error))
+	}
+	return req.RowsAffected > 0, nil
+}
+
+func (r *ReadMarkerPostgresRepository) GetReadMarkers(conversationId string) ([]*domain.ReadMarker, error) {
+	var markers []*domain.ReadMarker
+	req := r.db.Where("conversation_id = ?", conversationId).Find(&markers)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("read markers not found: %v", req.Error))
+	}
+	return markers, nil
+}
+
+func (r *ReadMarkerPostgresRepository) GetUserReadMarkers(userId string) ([]*domain.ReadMarker, error) {
+	var markers []*domain.ReadMarker
+	req := r.db.Where("user_id = ?", userId).Find(&markers)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("read markers not found: %v", req.Error))
+	}
+	return markers, nil
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type ReceiptPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewReceiptPostgresRepository() *ReceiptPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.DeliveryMarker{})
+	db.Model(&domain.DeliveryMarker{}).AddUniqueIndex("idx_delivery_markers_conversation_user", "conversation_id", "user_id")
+
+	return &ReceiptPostgresRepository{
+		db: db,
+	}
+}
+
+func (r *ReceiptPostgresRepository) SetDeliveryMarker(marker domain.DeliveryMarker) (bool, error) {
+	req := r.db.Exec(`
+		INSERT INTO delivery_markers (conversation_id, user_id, last_delivered_message_id, last_delivered_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (conversation_id, user_id) DO UPDATE SET
+			last_delivered_message_id = EXCLUDED.last_delivered_message_id,
+			last_delivered_at = EXCLUDED.last_delivered_at,
+			updated_at = EXCLUDED.updated_at
+		WHERE delivery_markers.last_delivered_at < EXCLUDED.last_delivered_at`,
+		marker.ConversationId, marker.UserId, marker.LastDeliveredMessageId, marker.LastDeliveredAt, marker.UpdatedAt)
+	if req.Error != nil {
+		return false, errors.New(fmt.Sprintf("delivery marker not saved: %v", req.Error))
+	}
+	return req.RowsAffected > 0, nil
+}
+
+func (r *ReceiptPostgresRepository) GetDeliveryMarkers(conversationId string) ([]*domain.DeliveryMarker, error) {
+	var markers []*domain.DeliveryMarker
+	req := r.db.Where("conversation_id = ?", conversationId).Find(&markers)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("delivery markers not found: %v", req.Error))
+	}
+	return markers, nil
+}
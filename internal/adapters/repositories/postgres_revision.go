@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type RevisionPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewRevisionPostgresRepository() *RevisionPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.MessageRevision{})
+	db.Model(&domain.MessageRevision{}).AddIndex("idx_message_revisions_message_edited", "message_id", "edited_at")
+
+	return &RevisionPostgresRepository{
+		db: db,
+	}
+}
+
+func (r *RevisionPostgresRepository) AddRevision(revision domain.MessageRevision) error {
+	req := r.db.Create(&revision)
+	if req.RowsAffected == 0 {
+		return errors.New(fmt.Sprintf("revision not saved: %v", req.Error))
+	}
+	return nil
+}
+
+func (r *RevisionPostgresRepository) GetRevisions(messageId string) ([]*domain.MessageRevision, error) {
+	var revisions []*domain.MessageRevision
+	req := r.db.Where("message_id = ?", messageId).Order("edited_at").Find(&revisions)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("revisions not found: %v", req.Error))
+	}
+	return revisions, nil
+}
+
+func (r *RevisionPostgresRepository) DeleteRevision(id string) error {
+	req := r.db.Where("id = ?", id).Delete(&domain.MessageRevision{})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to delete revision: %v", req.Error))
+	}
+	return nil
+}
+
+func (r *RevisionPostgresRepository) DeleteRevisions(messageIds []string) error {
+	req := r.db.Where("message_id IN (?)", messageIds).Delete(&domain.MessageRevision{})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to delete revisions: %v", req.Error))
+	}
+	return nil
+}
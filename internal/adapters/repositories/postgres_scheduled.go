@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type ScheduledMessagePostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduledMessagePostgresRepository() *ScheduledMessagePostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.ScheduledMessage{})
+	db.Model(&domain.ScheduledMessage{}).AddIndex("idx_scheduled_messages_status_send_at", "status", "send_at")
+	db.Model(&domain.ScheduledMessage{}).AddIndex("idx_scheduled_messages_user", "user_id", "status")
+
+	return &ScheduledMessagePostgresRepository{
+		db: db,
+	}
+}
+
+func (s *ScheduledMessagePostgresRepository) AddScheduledMessage(scheduled domain.ScheduledMessage) error {
+	req := s.db.Create(&scheduled)
+	if req.RowsAffected == 0 {
+		return errors.New(fmt.Sprintf("scheduled message not saved: %v", req.Error))
+	}
+	return nil
+}
+
+func (s *ScheduledMessagePostgresRepository) GetScheduledMessages(userId, conversationId string) ([]*domain.ScheduledMessage, error) {
+	var scheduled []*domain.ScheduledMessage
+
+	query := s.db.Where("user_id = ? AND status IN (?)", userId, []string{domain.ScheduledPending, domain.ScheduledFailed})
+	if conversationId != "" {
+		query = query.Where("conversation_id = ?", conversationId)
+	}
+
+	req := query.Order("send_at").Find(&scheduled)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("scheduled messages not found: %v", req.Error))
+	}
+	return scheduled, nil
+}
+
+func (s *ScheduledMessagePostgresRepository) UpdateScheduledMessage(id, userId, body string, sendAt, updatedAt time.Time) (*domain.ScheduledMessage, error) {
+	req := s.db.Model(&domain.ScheduledMessage{}).
+		Where("id = ? AND user_id = ? AND status IN (?)", id, userId, []string{domain.ScheduledPending, domain.ScheduledFailed}).
+		Updates(map[string]interface{}{
+			"body":       body,
+			"send_at":    sendAt,
+			"status":     domain.ScheduledPending,
+			"error":      "",
+			"updated_at": updatedAt,
+		})
+	if req.RowsAffected == 0 {
+		return nil, errors.New("scheduled message not found or already sent")
+	}
+
+	scheduled := &domain.ScheduledMessage{}
+	req = s.db.First(&scheduled, "id = ?", id)
+	if req.RowsAffected == 0 {
+		return nil, errors.New(fmt.Sprintf("scheduled message not found: %v", req.Error))
+	}
+	return scheduled, nil
+}
+
+func (s *ScheduledMessagePostgresRepository) CancelScheduledMessage(id, userId string, cancelledAt time.Time) error {
+	req := s.db.Model(&domain.ScheduledMessage{}).
+		Where("id = ? AND user_id = ? AND status IN (?)", id, userId, []string{domain.ScheduledPending, domain.ScheduledFailed}).
+		Updates(map[string]interface{}{"status": domain.ScheduledCancelled, "updated_at": cancelledAt})
+	if req.RowsAffected == 0 {
+		return errors.New("scheduled message not found or already sent")
+	}
+	return nil
+}
+
+// ClaimDueMessages leases due messages to one instance. SKIP LOCKED keeps two
+// instances from claiming the same rows, and an expired lease is claimable again.
+func (s *ScheduledMessagePostgresRepository) ClaimDueMessages(now time.Time, owner string, lease time.Duration, limit int) ([]*domain.ScheduledMessage, error) {
+	var scheduled []*domain.ScheduledMessage
+	req := s.db.Raw(`UPDATE scheduled_messages SET status = ?, claimed_by = ?, claimed_until = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM scheduled_messages
+			WHERE (status = ? AND send_at <= ?) OR (status = ? AND claimed_until < ?)
+			ORDER BY send_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		domain.ScheduledSending, owner, now.Add(lease), now,
+		domain.ScheduledPending, now, domain.ScheduledSending, now,
+		limit,
+	).Scan(&scheduled)
+	if req.Error != nil && !gorm.IsRecordNotFoundError(req.Error) {
+		return nil, errors.New(fmt.Sprintf("scheduled messages not claimed: %v", req.Error))
+	}
+	return scheduled, nil
+}
+
+func (s *ScheduledMessagePostgresRepository) FinishScheduledMessage(id, owner, status, errorMessage string, finishedAt time.Time) error {
+	updates := map[string]interface{}{
+		"status":        status,
+		"error":         errorMessage,
+		"claimed_until": nil,
+		"updated_at":    finishedAt,
+	}
+	if status == domain.ScheduledSent {
+		updates["sent_at"] = finishedAt
+	}
+
+	req := s.db.Model(&domain.ScheduledMessage{}).
+		Where("id = ? AND claimed_by = ? AND status = ?", id, owner, domain.ScheduledSending).
+		Updates(updates)
+	if req.RowsAffected == 0 {
+		return errors.New("scheduled message is no longer claimed")
+	}
+	return nil
+}
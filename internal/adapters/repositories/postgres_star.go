@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type StarPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewStarPostgresRepository() *StarPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.Star{})
+	db.Model(&domain.Star{}).AddUniqueIndex("idx_stars_user_message", "user_id", "message_id")
+	db.Model(&domain.Star{}).AddIndex("idx_stars_user_created", "user_id", "created_at", "id")
+
+	return &StarPostgresRepository{
+		db: db,
+	}
+}
+
+func (s *StarPostgresRepository) SetStar(star domain.Star) (*domain.Star, error) {
+	req := s.db.Exec(`INSERT INTO stars (id, user_id, message_id, conversation_id, note, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, message_id) DO UPDATE SET note = EXCLUDED.note, updated_at = EXCLUDED.updated_at`,
+		star.Id, star.UserId, star.MessageId, star.ConversationId, star.Note, star.CreatedAt, star.UpdatedAt)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("star not saved: %v", req.Error))
+	}
+
+	saved := &domain.Star{}
+	req = s.db.First(&saved, "user_id = ? AND message_id = ?", star.UserId, star.MessageId)
+	if req.RowsAffected == 0 {
+		return nil, errors.New(fmt.Sprintf("star not found: %v", req.Error))
+	}
+	return saved, nil
+}
+
+func (s *StarPostgresRepository) RemoveStar(userId, messageId string) error {
+	req := s.db.Where("user_id = ? AND message_id = ?", userId, messageId).Delete(&domain.Star{})
+	if req.RowsAffected == 0 {
+		return errors.New("star not found")
+	}
+	return nil
+}
+
+func (s *StarPostgresRepository) GetStars(userId string, query domain.PageQuery) (*domain.StarPage, error) {
+	cursor, forward, err := query.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	var stars []*domain.Star
+	req := keysetPage(s.db.Where("user_id = ?", userId), cursor, forward, query.Limit).Find(&stars)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("stars not found: %v", req.Error))
+	}
+
+	page := &domain.StarPage{Stars: stars}
+	if len(stars) > query.Limit {
+		page.Stars = stars[:query.Limit]
+		last := page.Stars[query.Limit-1]
+		page.NextCursor = domain.EncodeCursor(last.CreatedAt, last.Id)
+	}
+	if forward {
+		reverseStars(page.Stars)
+	}
+	return page, nil
+}
+
+func (s *StarPostgresRepository) DeleteStars(messageIds []string) error {
+	req := s.db.Where("message_id IN (?)", messageIds).Delete(&domain.Star{})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to delete stars: %v", req.Error))
+	}
+	return nil
+}
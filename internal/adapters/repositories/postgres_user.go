@@ -3,14 +3,12 @@ package repositories
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jinzhu/gorm"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 	"messenger/internal/core/domain"
 )
@@ -26,19 +24,10 @@ type UserPostgresRepository struct {
 }
 
 func NewUserPostgresRepository() *UserPostgresRepository {
-	err := godotenv.Load(".env")
-
-	if err != nil {
-		log.Fatal("Error loading file .env")
-	}
-
-	connStr := os.Getenv("POSTGRES_USER_URL")
-
-	db, err := gorm.Open("postgres", connStr)
-	if err != nil {
-		panic(err)
-	}
+	db := newPostgresDB("POSTGRES_USER_URL")
 	db.AutoMigrate(&domain.User{})
+	db.Model(&domain.User{}).AddIndex("idx_users_created", "created_at", "id")
+	db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_handle ON users (handle) WHERE handle <> ''")
 
 	return &UserPostgresRepository{
 		db: db,
@@ -76,13 +65,28 @@ func (u *UserPostgresRepository) GetOneUser(id string) (*domain.User, error) {
 	return user, nil
 }
 
-func (u *UserPostgresRepository) GetAllUsers() ([]*domain.User, error) {
+func (u *UserPostgresRepository) GetAllUsers(query domain.PageQuery) (*domain.UserPage, error) {
+	cursor, forward, err := query.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
 	var users []*domain.User
-	req := u.db.Find(&users)
-	if req.RowsAffected == 0 {
+	req := keysetPage(u.db, cursor, forward, query.Limit).Find(&users)
+	if req.Error != nil {
 		return nil, errors.New(fmt.Sprintf("users not found: %v", req.Error))
 	}
-	return users, nil
+
+	page := &domain.UserPage{Users: users}
+	if len(users) > query.Limit {
+		page.Users = users[:query.Limit]
+		last := page.Users[query.Limit-1]
+		page.NextCursor = domain.EncodeCursor(last.CreatedAt, last.Id)
+	}
+	if forward {
+		reverseUsers(page.Users)
+	}
+	return page, nil
 }
 
 func (u *UserPostgresRepository) LoginUser(email, password string) (*LoginResponse, error) {
@@ -181,3 +185,50 @@ func (u *UserPostgresRepository) UserExist(email string) error {
 	}
 	return nil
 }
+
+func (u *UserPostgresRepository) GetUsersByEmails(emails []string) ([]*domain.User, error) {
+	var users []*domain.User
+	if len(emails) == 0 {
+		return users, nil
+	}
+
+	req := u.db.Where("lower(email) IN (?)", emails).Find(&users)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("users not found: %v", req.Error))
+	}
+	return users, nil
+}
+
+func (u *UserPostgresRepository) GetUsersByHandles(handles []string) ([]*domain.User, error) {
+	var users []*domain.User
+	if len(handles) == 0 {
+		return users, nil
+	}
+
+	req := u.db.Where("handle IN (?)", handles).Find(&users)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("users not found: %v", req.Error))
+	}
+	return users, nil
+}
+
+func (u *UserPostgresRepository) GetUsers(ids []string) ([]*domain.User, error) {
+	var users []*domain.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	req := u.db.Where("id IN (?)", ids).Find(&users)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("users not found: %v", req.Error))
+	}
+	return users, nil
+}
+
+func (u *UserPostgresRepository) SetLastSeen(id string, lastSeenAt time.Time) error {
+	req := u.db.Model(&domain.User{}).Where("id = ?", id).UpdateColumn("last_seen_at", lastSeenAt)
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("last seen not saved: %v", req.Error))
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"messenger/internal/core/domain"
+)
+
+type webhookEventRow struct {
+	WebhookId string
+	Event     string
+}
+
+func (webhookEventRow) TableName() string {
+	return "webhook_events"
+}
+
+type WebhookPostgresRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookPostgresRepository() *WebhookPostgresRepository {
+	db := newPostgresDB("POSTGRES_MESSANGER_URL")
+	db.AutoMigrate(&domain.Webhook{}, &webhookEventRow{}, &domain.WebhookDelivery{})
+	db.Model(&webhookEventRow{}).AddUniqueIndex("idx_webhook_events_webhook_event", "webhook_id", "event")
+	db.Model(&domain.WebhookDelivery{}).AddIndex("idx_webhook_deliveries_status_next_attempt", "status", "next_attempt_at")
+	db.Model(&domain.WebhookDelivery{}).AddIndex("idx_webhook_deliveries_webhook", "webhook_id", "created_at")
+	db.Model(&domain.WebhookDelivery{}).AddIndex("idx_webhook_deliveries_message", "message_id")
+
+	return &WebhookPostgresRepository{
+		db: db,
+	}
+}
+
+func (w *WebhookPostgresRepository) AddWebhook(webhook domain.Webhook) error {
+	tx := w.db.Begin()
+	if err := tx.Create(&webhook).Error; err != nil {
+		tx.Rollback()
+		return errors.New(fmt.Sprintf("webhook not saved: %v", err))
+	}
+
+	for _, event := range webhook.Events {
+		if err := tx.Create(&webhookEventRow{WebhookId: webhook.Id, Event: event}).Error; err != nil {
+			tx.Rollback()
+			return errors.New(fmt.Sprintf("webhook not saved: %v", err))
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New(fmt.Sprintf("webhook not saved: %v", err))
+	}
+	return nil
+}
+
+func (w *WebhookPostgresRepository) GetWebhooks() ([]*domain.Webhook, error) {
+	var webhooks []*domain.Webhook
+	if err := w.db.Order("created_at").Find(&webhooks).Error; err != nil {
+		return nil, errors.New(fmt.Sprintf("webhooks not found: %v", err))
+	}
+
+	var rows []*webhookEventRow
+	if err := w.db.Find(&rows).Error; err != nil {
+		return nil, errors.New(fmt.Sprintf("webhooks not found: %v", err))
+	}
+
+	events := make(map[string][]string)
+	for _, row := range rows {
+		events[row.WebhookId] = append(events[row.WebhookId], row.Event)
+	}
+	for _, webhook := range webhooks {
+		webhook.Events = events[webhook.Id]
+	}
+	return webhooks, nil
+}
+
+func (w *WebhookPostgresRepository) GetWebhook(id string) (*domain.Webhook, error) {
+	webhook := &domain.Webhook{}
+	req := w.db.First(&webhook, "id = ?", id)
+	if req.RowsAffected == 0 {
+		return nil, errors.New(fmt.Sprintf("webhook not found: %v", req.Error))
+	}
+
+	var rows []*webhookEventRow
+	if err := w.db.Where("webhook_id = ?", id).Find(&rows).Error; err != nil {
+		return nil, errors.New(fmt.Sprintf("webhook not found: %v", err))
+	}
+	for _, row := range rows {
+		webhook.Events = append(webhook.Events, row.Event)
+	}
+	return webhook, nil
+}
+
+func (w *WebhookPostgresRepository) DeleteWebhook(id string) error {
+	tx := w.db.Begin()
+	req := tx.Where("id = ?", id).Delete(&domain.Webhook{})
+	if req.Error != nil {
+		tx.Rollback()
+		return errors.New(fmt.Sprintf("unable to delete webhook: %v", req.Error))
+	}
+	if req.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.New("webhook not found")
+	}
+
+	for _, model := range []interface{}{&webhookEventRow{}, &domain.WebhookDelivery{}} {
+		if err := tx.Where("webhook_id = ?", id).Delete(model).Error; err != nil {
+			tx.Rollback()
+			return errors.New(fmt.Sprintf("unable to delete webhook: %v", err))
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New(fmt.Sprintf("unable to delete webhook: %v", err))
+	}
+	return nil
+}
+
+func (w *WebhookPostgresRepository) AddDeliveries(deliveries []domain.WebhookDelivery) error {
+	tx := w.db.Begin()
+	for _, delivery := range deliveries {
+		if err := tx.Create(&delivery).Error; err != nil {
+			tx.Rollback()
+			return errors.New(fmt.Sprintf("webhook deliveries not saved: %v", err))
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New(fmt.Sprintf("webhook deliveries not saved: %v", err))
+	}
+	return nil
+}
+
+func (w *WebhookPostgresRepository) GetDeliveries(webhookId, status string, limit int) ([]*domain.WebhookDelivery, error) {
+	var deliveries []*domain.WebhookDelivery
+
+	query := w.db.Where("webhook_id = ?", webhookId)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	req := query.Order("created_at DESC").Limit(limit).Find(&deliveries)
+	if req.Error != nil {
+		return nil, errors.New(fmt.Sprintf("webhook deliveries not found: %v", req.Error))
+	}
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries leases due deliveries to one instance, the same way
+// scheduled messages are claimed.
+func (w *WebhookPostgresRepository) ClaimDueDeliveries(now time.Time, owner string, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	var deliveries []*domain.WebhookDelivery
+	req := w.db.Raw(`UPDATE webhook_deliveries SET status = ?, claimed_by = ?, claimed_until = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE (status = ? AND next_attempt_at <= ?) OR (status = ? AND claimed_until < ?)
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		domain.WebhookSending, owner, now.Add(lease), now,
+		domain.WebhookPending, now, domain.WebhookSending, now,
+		limit,
+	).Scan(&deliveries)
+	if req.Error != nil && !gorm.IsRecordNotFoundError(req.Error) {
+		return nil, errors.New(fmt.Sprintf("webhook deliveries not claimed: %v", req.Error))
+	}
+	return deliveries, nil
+}
+
+func (w *WebhookPostgresRepository) FinishDelivery(delivery domain.WebhookDelivery, owner string) error {
+	req := w.db.Model(&domain.WebhookDelivery{}).
+		Where("id = ? AND claimed_by = ? AND status = ?", delivery.Id, owner, domain.WebhookSending).
+		Updates(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"response_status": delivery.ResponseStatus,
+			"error":           delivery.Error,
+			"payload":         delivery.Payload,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"delivered_at":    delivery.DeliveredAt,
+			"claimed_until":   nil,
+			"updated_at":      delivery.UpdatedAt,
+		})
+	if req.RowsAffected == 0 {
+		return errors.New("webhook delivery is no longer claimed")
+	}
+	return nil
+}
+
+func (w *WebhookPostgresRepository) RetryDelivery(id, webhookId string, now time.Time) error {
+	req := w.db.Model(&domain.WebhookDelivery{}).
+		Where("id = ? AND webhook_id = ? AND status = ?", id, webhookId, domain.WebhookDead).
+		Updates(map[string]interface{}{
+			"status":          domain.WebhookPending,
+			"attempts":        0,
+			"error":           "",
+			"next_attempt_at": now,
+			"updated_at":      now,
+		})
+	if req.RowsAffected == 0 {
+		return errors.New("webhook delivery not found or not dead-lettered")
+	}
+	return nil
+}
+
+// PruneDeliveries removes delivered and dead-lettered deliveries last touched before a time.
+func (w *WebhookPostgresRepository) PruneDeliveries(before time.Time) (int, error) {
+	req := w.db.Where("status IN (?) AND updated_at < ?", []string{domain.WebhookDelivered, domain.WebhookDead}, before).Delete(&domain.WebhookDelivery{})
+	if req.Error != nil {
+		return 0, errors.New(fmt.Sprintf("unable to prune webhook deliveries: %v", req.Error))
+	}
+	return int(req.RowsAffected), nil
+}
+
+// DeleteMessageDeliveries drops the undelivered payloads of messages that no longer exist.
+func (w *WebhookPostgresRepository) DeleteMessageDeliveries(messageIds []string) error {
+	if len(messageIds) == 0 {
+		return nil
+	}
+
+	req := w.db.Where("message_id IN (?) AND status IN (?)", messageIds, []string{domain.WebhookPending, domain.WebhookDead}).Delete(&domain.WebhookDelivery{})
+	if req.Error != nil {
+		return errors.New(fmt.Sprintf("unable to delete webhook deliveries: %v", req.Error))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const snippetRadius = 60
+
+// highlightSnippet mirrors the ts_headline output of the Postgres adapter: an escaped
+// fragment of the body around the first match, with matched words wrapped in <mark>.
+func highlightSnippet(body, query string) string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	words := strings.FieldsFunc(body, unicode.IsSpace)
+	first := -1
+	for i, word := range words {
+		if matchesTerm(word, terms) {
+			first = i
+			break
+		}
+	}
+
+	start, end := 0, len(words)
+	if first >= 0 {
+		start, end = first, first
+		for start > 0 && len(strings.Join(words[start-1:first], " ")) < snippetRadius {
+			start--
+		}
+		for end < len(words) && len(strings.Join(words[first:end+1], " ")) < snippetRadius*2 {
+			end++
+		}
+	}
+
+	parts := make([]string, 0, end-start)
+	for _, word := range words[start:end] {
+		escaped := html.EscapeString(word)
+		if matchesTerm(word, terms) {
+			escaped = "<mark>" + escaped + "</mark>"
+		}
+		parts = append(parts, escaped)
+	}
+
+	snippet := strings.Join(parts, " ")
+	if start > 0 {
+		snippet = "... " + snippet
+	}
+	if end < len(words) {
+		snippet += " ..."
+	}
+	return snippet
+}
+
+func matchesTerm(word string, terms []string) bool {
+	word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+	for _, term := range terms {
+		if word == term {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type LocalBlobStorage struct {
+	root string
+}
+
+func NewLocalBlobStorage(root string) *LocalBlobStorage {
+	return &LocalBlobStorage{
+		root: root,
+	}
+}
+
+func (l *LocalBlobStorage) Put(key string, body io.Reader, size int64, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.New(fmt.Sprintf("blob not saved: %v", err))
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return errors.New(fmt.Sprintf("blob not saved: %v", err))
+	}
+	defer os.Remove(file.Name())
+
+	written, err := io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("blob not saved: %v", err))
+	}
+
+	if size >= 0 && written != size {
+		return errors.New("blob not saved: size mismatch")
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return errors.New(fmt.Sprintf("blob not saved: %v", err))
+	}
+	return nil
+}
+
+func (l *LocalBlobStorage) Get(key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New("blob not found")
+	}
+	return file, nil
+}
+
+func (l *LocalBlobStorage) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.New(fmt.Sprintf("blob not deleted: %v", err))
+	}
+	return nil
+}
+
+func (l *LocalBlobStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", errors.New("invalid blob key")
+	}
+	return filepath.Join(l.root, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalBlobStorageRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	local := NewLocalBlobStorage(root)
+
+	key := "conversations/c1/photo.png"
+	content := []byte("\x89PNG not really an image")
+	if err := local.Put(key, bytes.NewReader(content), int64(len(content)), "image/png"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	if stored, err := os.ReadFile(filepath.Join(root, "conversations", "c1", "photo.png")); err != nil || !bytes.Equal(stored, content) {
+		t.Fatalf("stored %q, %v, want %q", stored, err, content)
+	}
+
+	body, err := local.Get(key)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	got, err := io.ReadAll(body)
+	body.Close()
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("get returned %q, %v, want %q", got, err, content)
+	}
+
+	if err := local.Delete(key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := local.Get(key); err == nil {
+		t.Fatal("get after delete succeeded")
+	}
+	if err := local.Delete(key); err != nil {
+		t.Errorf("deleting a missing blob: %v", err)
+	}
+}
+
+func TestLocalBlobStorageSizeMismatch(t *testing.T) {
+	root := t.TempDir()
+	local := NewLocalBlobStorage(root)
+
+	if err := local.Put("a.txt", strings.NewReader("abc"), 10, "text/plain"); err == nil {
+		t.Fatal("put with a wrong size succeeded")
+	}
+
+	// neither the blob nor its temporary file may be left behind
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("left %d files behind", len(entries))
+	}
+}
+
+func TestLocalBlobStorageRejectsKeys(t *testing.T) {
+	parent := t.TempDir()
+	local := NewLocalBlobStorage(filepath.Join(parent, "blobs"))
+
+	for _, key := range []string{"", "/", "../escape.txt", "a/../../escape.txt"} {
+		if err := local.Put(key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("put %q succeeded", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(parent, "escape.txt")); !os.IsNotExist(err) {
+		t.Error("a blob was written outside the root")
+	}
+}
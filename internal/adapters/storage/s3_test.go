@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "test-access"
+	testSecretKey = "test-secret"
+	testRegion    = "eu-test-1"
+	testBucket    = "attachments"
+)
+
+var authorizationPattern = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/s3/aws4_request, SignedHeaders=([^,]+), Signature=([0-9a-f]{64})$`)
+
+// fakeS3 keeps objects in memory and refuses any request whose SigV4 signature
+// does not match the one it computes itself.
+type fakeS3 struct {
+	t        *testing.T
+	mu       sync.Mutex
+	objects  map[string][]byte
+	types    map[string]string
+	requests int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{t: t, objects: make(map[string][]byte), types: make(map[string]string)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	if err := f.verify(r); err != nil {
+		if f.t != nil {
+			f.t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		}
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	key := r.URL.Path
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if r.ContentLength != int64(len(body)) {
+			f.t.Errorf("PUT %s: content length %d for %d bytes", key, r.ContentLength, len(body))
+		}
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) verify(r *http.Request) error {
+	match := authorizationPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+	if match == nil {
+		return fmt.Errorf("malformed authorization %q", r.Header.Get("Authorization"))
+	}
+	accessKey, day, region, signedHeaders, signature := match[1], match[2], match[3], match[4], match[5]
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	at, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return fmt.Errorf("malformed x-amz-date %q", amzDate)
+	}
+	if at.Format("20060102") != day {
+		return fmt.Errorf("credential day %s does not match x-amz-date %s", day, amzDate)
+	}
+	if time.Since(at).Abs() > 15*time.Minute {
+		return fmt.Errorf("x-amz-date %s is too far off", amzDate)
+	}
+	if accessKey != testAccessKey || region != testRegion {
+		return fmt.Errorf("credential %s/%s, want %s/%s", accessKey, region, testAccessKey, testRegion)
+	}
+	if r.Header.Get("X-Amz-Content-Sha256") != "UNSIGNED-PAYLOAD" {
+		return fmt.Errorf("x-amz-content-sha256 is %q", r.Header.Get("X-Amz-Content-Sha256"))
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + day + "/" + region + "/s3/aws4_request\n" + hex.EncodeToString(hashed[:])
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		key = testHmac(key, part)
+	}
+	if want := hex.EncodeToString(testHmac(key, stringToSign)); signature != want {
+		return fmt.Errorf("signature %s, want %s", signature, want)
+	}
+	return nil
+}
+
+func testHmac(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func TestS3BlobStorageRoundTrip(t *testing.T) {
+	fake, server := newFakeS3(t)
+
+	s3, err := NewS3BlobStorage(server.URL, testRegion, testBucket, testAccessKey, testSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "conversations/c1/report 2024 (final)+v2.pdf"
+	content := []byte("%PDF-1.7 not really a pdf")
+	if err := s3.Put(key, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	stored := "/" + testBucket + "/" + key
+	if !bytes.Equal(fake.objects[stored], content) {
+		t.Fatalf("stored %q under %q, want %q", fake.objects[stored], stored, content)
+	}
+	if fake.types[stored] != "application/pdf" {
+		t.Errorf("content type %q, want application/pdf", fake.types[stored])
+	}
+
+	body, err := s3.Get(key)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	got, err := io.ReadAll(body)
+	body.Close()
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("get returned %q, %v, want %q", got, err, content)
+	}
+
+	if err := s3.Delete(key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s3.Get(key); err == nil {
+		t.Fatal("get after delete succeeded")
+	}
+}
+
+func TestS3BlobStorageRefusedSignature(t *testing.T) {
+	_, server := newFakeS3(t)
+
+	s3, err := NewS3BlobStorage(server.URL, testRegion, testBucket, testAccessKey, "wrong-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the mismatch is expected here, so the fake must not report it
+	fake := &fakeS3{objects: make(map[string][]byte), types: make(map[string]string)}
+	server.Config.Handler = fake
+
+	err = s3.Put("a.txt", strings.NewReader("a"), 1, "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("put with a wrong secret = %v, want a 403 error", err)
+	}
+	if len(fake.objects) != 0 {
+		t.Errorf("object stored despite a bad signature")
+	}
+}
+
+func TestS3BlobStorageRejectsKeys(t *testing.T) {
+	fake, server := newFakeS3(t)
+
+	s3, err := NewS3BlobStorage(server.URL, testRegion, testBucket, testAccessKey, testSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "../other-bucket/x", "a/../../b"} {
+		if err := s3.Put(key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("put %q succeeded", key)
+		}
+	}
+	if fake.requests != 0 {
+		t.Errorf("%d requests sent for invalid keys", fake.requests)
+	}
+}
+
+func TestNewS3BlobStorageValidates(t *testing.T) {
+	if _, err := NewS3BlobStorage("not a url", "", testBucket, "", ""); err == nil {
+		t.Error("invalid endpoint accepted")
+	}
+	if _, err := NewS3BlobStorage("http://127.0.0.1:9000", "", "", "", ""); err == nil {
+		t.Error("missing bucket accepted")
+	}
+}
+
+// TestS3BlobStorageAgainstServer runs the round trip against a real S3-compatible
+// server, such as a local MinIO, when S3_TEST_ENDPOINT is set.
+func TestS3BlobStorageAgainstServer(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT is not set")
+	}
+
+	s3, err := NewS3BlobStorage(endpoint, os.Getenv("S3_TEST_REGION"), os.Getenv("S3_TEST_BUCKET"), os.Getenv("S3_TEST_ACCESS_KEY"), os.Getenv("S3_TEST_SECRET_KEY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := fmt.Sprintf("storage-test/%d/hello world.txt", time.Now().UnixNano())
+	content := []byte("hello from the storage test")
+	if err := s3.Put(key, bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	defer s3.Delete(key)
+
+	body, err := s3.Get(key)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(got, content) {
+		t.Fatalf("get returned %q, want %q", got, content)
+	}
+
+	if err := s3.Delete(key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s3.Get(key); err == nil {
+		t.Fatal("get after delete succeeded")
+	}
+}
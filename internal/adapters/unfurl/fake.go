@@ -0,0 +1,57 @@
+package unfurl
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+
+	"messenger/internal/core/domain"
+)
+
+// FakeUnfurler answers from a fixed set of previews without touching the network.
+// Links it does not know get a preview titled with their host, and every call is
+// recorded so tests can assert on what was unfurled.
+type FakeUnfurler struct {
+	mu       sync.Mutex
+	previews map[string]*domain.LinkPreview
+	calls    []string
+}
+
+func NewFakeUnfurler(previews map[string]*domain.LinkPreview) *FakeUnfurler {
+	if previews == nil {
+		previews = make(map[string]*domain.LinkPreview)
+	}
+
+	return &FakeUnfurler{
+		previews: previews,
+	}
+}
+
+func (f *FakeUnfurler) Unfurl(rawUrl string) (*domain.LinkPreview, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, rawUrl)
+
+	if preview, ok := f.previews[rawUrl]; ok {
+		if preview == nil {
+			return nil, errors.New("page has no preview metadata")
+		}
+		copied := *preview
+		copied.Url = rawUrl
+		return &copied, nil
+	}
+
+	target, err := url.Parse(rawUrl)
+	if err != nil || target.Hostname() == "" {
+		return nil, errors.New("invalid url")
+	}
+	return &domain.LinkPreview{Url: rawUrl, Title: target.Hostname()}, nil
+}
+
+func (f *FakeUnfurler) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.calls...)
+}
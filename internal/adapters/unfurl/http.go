@@ -0,0 +1,279 @@
+package unfurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"messenger/internal/adapters/netguard"
+	"messenger/internal/core/domain"
+)
+
+const (
+	DefaultTimeout  = 5 * time.Second
+	DefaultMaxBytes = 1 << 20
+
+	maxRedirects         = 5
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+	userAgent            = "messenger-unfurler/1.0"
+)
+
+var ErrBlockedAddress = netguard.ErrBlockedAddress
+
+// HTTPUnfurler reads OpenGraph, Twitter card and oEmbed metadata from web pages.
+// Every connection is checked after DNS resolution, so hosts that resolve to
+// private addresses are refused even behind redirects or rebinding.
+type HTTPUnfurler struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func NewHTTPUnfurler(timeout time.Duration, maxBytes int64) *HTTPUnfurler {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: netguard.Control,
+	}
+
+	transport := &http.Transport{
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    timeout,
+		ResponseHeaderTimeout:  timeout,
+		MaxResponseHeaderBytes: 64 << 10,
+		MaxIdleConns:           10,
+		IdleConnTimeout:        30 * time.Second,
+	}
+
+	return &HTTPUnfurler{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return errors.New("too many redirects")
+				}
+				return checkUrl(req.URL)
+			},
+		},
+		maxBytes: maxBytes,
+	}
+}
+
+func (u *HTTPUnfurler) Unfurl(rawUrl string) (*domain.LinkPreview, error) {
+	target, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid url: %v", err))
+	}
+	if err := checkUrl(target); err != nil {
+		return nil, err
+	}
+
+	body, final, err := u.fetch(target.String(), "text/html", "application/xhtml+xml")
+	if err != nil {
+		return nil, err
+	}
+
+	meta := parseHead(body)
+	preview := &domain.LinkPreview{
+		Url:         rawUrl,
+		Type:        first(meta["og:type"]),
+		Title:       first(meta["og:title"], meta["twitter:title"], meta["title"]),
+		Description: first(meta["og:description"], meta["twitter:description"], meta["description"]),
+		SiteName:    first(meta["og:site_name"]),
+		ImageUrl:    resolve(final, first(meta["og:image"], meta["og:image:url"], meta["twitter:image"])),
+	}
+
+	if oembed := resolve(final, meta["oembed"]); oembed != "" && (preview.Title == "" || preview.ImageUrl == "") {
+		u.addOEmbed(preview, oembed)
+	}
+
+	preview.Title = clip(preview.Title, maxTitleLength)
+	preview.Description = clip(preview.Description, maxDescriptionLength)
+	preview.SiteName = clip(preview.SiteName, maxTitleLength)
+	if preview.Title == "" && preview.Description == "" {
+		return nil, errors.New("page has no preview metadata")
+	}
+	return preview, nil
+}
+
+func (u *HTTPUnfurler) addOEmbed(preview *domain.LinkPreview, endpoint string) {
+	body, final, err := u.fetch(endpoint, "application/json", "text/json")
+	if err != nil {
+		return
+	}
+
+	var data struct {
+		Type         string `json:"type"`
+		Title        string `json:"title"`
+		AuthorName   string `json:"author_name"`
+		ProviderName string `json:"provider_name"`
+		ThumbnailUrl string `json:"thumbnail_url"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return
+	}
+
+	if preview.Title == "" {
+		preview.Title = strings.TrimSpace(data.Title)
+	}
+	if preview.Type == "" {
+		preview.Type = strings.TrimSpace(data.Type)
+	}
+	if preview.SiteName == "" {
+		preview.SiteName = strings.TrimSpace(data.ProviderName)
+	}
+	if preview.Description == "" {
+		preview.Description = strings.TrimSpace(data.AuthorName)
+	}
+	if preview.ImageUrl == "" {
+		preview.ImageUrl = resolve(final, data.ThumbnailUrl)
+	}
+}
+
+// fetch downloads at most maxBytes of a response and returns it with the final URL.
+func (u *HTTPUnfurler) fetch(rawUrl string, accepted ...string) ([]byte, *url.URL, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), u.client.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("invalid url: %v", err))
+	}
+	req.Header.Set("Accept", strings.Join(accepted, ", "))
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("page not fetched: %v", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, errors.New(fmt.Sprintf("page not fetched: %s", resp.Status))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !slices.Contains(accepted, mediaType) {
+		return nil, nil, errors.New(fmt.Sprintf("unsupported content type %q", mediaType))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, u.maxBytes))
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("page not fetched: %v", err))
+	}
+	return body, resp.Request.URL, nil
+}
+
+// parseHead collects the title, meta tags and oEmbed link of a page, stopping at <body>.
+func parseHead(body []byte) map[string]string {
+	meta := make(map[string]string)
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	inTitle := false
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return meta
+		case html.TextToken:
+			if inTitle && meta["title"] == "" {
+				meta["title"] = strings.TrimSpace(string(tokenizer.Text()))
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if string(name) == "title" {
+				inTitle = false
+			}
+			if string(name) == "head" {
+				return meta
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			attrs := make(map[string]string)
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = tokenizer.TagAttr()
+				attrs[strings.ToLower(string(key))] = string(value)
+			}
+
+			switch string(name) {
+			case "body":
+				return meta
+			case "title":
+				inTitle = true
+			case "meta":
+				key := strings.ToLower(attrs["property"])
+				if key == "" {
+					key = strings.ToLower(attrs["name"])
+				}
+				if key != "" && key != "title" && meta[key] == "" {
+					meta[key] = strings.TrimSpace(attrs["content"])
+				}
+			case "link":
+				if strings.EqualFold(attrs["type"], "application/json+oembed") && meta["oembed"] == "" {
+					meta["oembed"] = attrs["href"]
+				}
+			}
+		}
+	}
+}
+
+func checkUrl(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return errors.New("only http and https links can be previewed")
+	}
+	if target.User != nil || target.Hostname() == "" {
+		return errors.New("invalid url")
+	}
+
+	return netguard.CheckHost(target.Hostname())
+}
+
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+
+	target, err := base.Parse(ref)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return ""
+	}
+	return target.String()
+}
+
+func first(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func clip(value string, length int) string {
+	value = strings.ToValidUTF8(strings.Join(strings.Fields(value), " "), "")
+	if utf8.RuneCountInString(value) <= length {
+		return value
+	}
+	return strings.TrimSpace(string([]rune(value)[:length-1])) + "…"
+}
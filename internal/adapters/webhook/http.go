@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"messenger/internal/adapters/netguard"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+
+	maxResponseBytes = 64 << 10
+	userAgent        = "messenger-webhooks/1.0"
+)
+
+// HTTPWebhookSender posts webhook payloads. Redirects are not followed, so a
+// receiver that moved shows up as a failed delivery instead of a silent hop.
+// Like the link unfurler, it only connects to public addresses, checked after
+// DNS resolution, so a webhook cannot be pointed at an internal service.
+type HTTPWebhookSender struct {
+	client *http.Client
+}
+
+func NewHTTPWebhookSender(timeout time.Duration) *HTTPWebhookSender {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: netguard.Control,
+	}
+
+	transport := &http.Transport{
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    timeout,
+		ResponseHeaderTimeout:  timeout,
+		MaxResponseHeaderBytes: 64 << 10,
+		MaxIdleConns:           10,
+		IdleConnTimeout:        30 * time.Second,
+	}
+
+	return &HTTPWebhookSender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// CheckUrl refuses urls that can never be delivered to, so they are caught
+// when the webhook is registered instead of on every delivery.
+func (s *HTTPWebhookSender) CheckUrl(target string) error {
+	link, err := url.Parse(target)
+	if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Hostname() == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if link.User != nil {
+		return errors.New("url must not hold credentials")
+	}
+	if err := netguard.CheckHost(link.Hostname()); err != nil {
+		return errors.New("url must point to a public address")
+	}
+	return nil
+}
+
+func (s *HTTPWebhookSender) Send(url string, headers map[string]string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// drain a bounded part of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	return resp.StatusCode, nil
+}
@@ -1,19 +1,444 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	ConversationDirect = "direct"
+	ConversationGroup  = "group"
+)
+
+var (
+	ErrConversationNotFound     = errors.New("conversation not found")
+	ErrDirectConversationExists = errors.New("direct conversation already exists")
+	ErrPollClosed               = errors.New("poll is closed")
+)
+
+const DeletedMessageBody = "message deleted"
+
+const (
+	MessageText   = "text"
+	MessagePoll   = "poll"
+	MessageSystem = "system"
+)
 
 type Message struct {
-	Id        string    `json:"_id" bson:"_id"`
-	Body      string    `json:"body" bson:"body"`
+	Id              string     `json:"_id" bson:"_id"`
+	ConversationId  string     `json:"conversation_id" bson:"conversation_id"`
+	Type            string     `json:"type" bson:"type"`
+	Body            string     `json:"body" bson:"body"`
+	BodyHtml        string     `json:"body_html,omitempty" bson:"-" gorm:"-"`
+	UserId          string     `json:"user_id" bson:"user_id"`
+	BotName         string     `json:"bot_name,omitempty" bson:"bot_name"`
+	ParentId        string     `json:"parent_id,omitempty" bson:"parent_id"`
+	ThreadRootId    string     `json:"thread_root_id,omitempty" bson:"thread_root_id"`
+	ReplyCount      int        `json:"reply_count" bson:"reply_count"`
+	LastReplyAt     *time.Time `json:"last_reply_at,omitempty" bson:"last_reply_at"`
+	Edited          bool       `json:"edited" bson:"edited"`
+	EditedAt        *time.Time `json:"edited_at,omitempty" bson:"edited_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty" bson:"deleted_at"`
+	DeletedBy       string     `json:"deleted_by,omitempty" bson:"deleted_by"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty" bson:"expires_at"`
+	ExpireAfterRead int        `json:"expire_after_read,omitempty" bson:"expire_after_read"`
+
+	ForwardedFromId             string `json:"forwarded_from_id,omitempty" bson:"forwarded_from_id"`
+	ForwardedFromUserId         string `json:"forwarded_from_user_id,omitempty" bson:"forwarded_from_user_id"`
+	ForwardedFromConversationId string `json:"forwarded_from_conversation_id,omitempty" bson:"forwarded_from_conversation_id"`
+	QuoteId                     string `json:"quote_id,omitempty" bson:"quote_id"`
+
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" bson:"updated_at"`
+	SendAt    *time.Time `json:"send_at,omitempty" bson:"-" gorm:"-"`
+
+	Reactions   []ReactionSummary `json:"reactions,omitempty" bson:"-" gorm:"-"`
+	Attachments []*Attachment     `json:"attachments,omitempty" bson:"-" gorm:"-"`
+	Mentions    []*Mention        `json:"mentions,omitempty" bson:"-" gorm:"-"`
+	Quote       *MessageQuote     `json:"quote,omitempty" bson:"-" gorm:"-"`
+	Poll        *Poll             `json:"poll,omitempty" bson:"-" gorm:"-"`
+	Previews    []*LinkPreview    `json:"previews,omitempty" bson:"-" gorm:"-"`
+	Delivery    *DeliveryStatus   `json:"delivery,omitempty" bson:"-" gorm:"-"`
+}
+
+type Poll struct {
+	MessageId      string       `json:"message_id" bson:"_id" gorm:"primary_key"`
+	ConversationId string       `json:"conversation_id" bson:"conversation_id"`
+	Question       string       `json:"question" bson:"question"`
+	MultipleChoice bool         `json:"multiple_choice" bson:"multiple_choice"`
+	Anonymous      bool         `json:"anonymous" bson:"anonymous"`
+	ClosesAt       *time.Time   `json:"closes_at,omitempty" bson:"closes_at"`
+	ClosedAt       *time.Time   `json:"closed_at,omitempty" bson:"closed_at"`
+	CreatedAt      time.Time    `json:"created_at" bson:"created_at"`
+	Options        []PollOption `json:"options" bson:"options" gorm:"-"`
+
+	Closed     bool     `json:"closed" bson:"-" gorm:"-"`
+	TotalVotes int      `json:"total_votes" bson:"-" gorm:"-"`
+	MyVote     []string `json:"my_vote,omitempty" bson:"-" gorm:"-"`
+}
+
+type PollOption struct {
+	Id     string   `json:"id" bson:"id"`
+	Text   string   `json:"text" bson:"text"`
+	Votes  int      `json:"votes" bson:"-"`
+	Voters []string `json:"voters,omitempty" bson:"-"`
+}
+
+// PollVote is a user's whole ballot: one per user and poll, holding every chosen option.
+type PollVote struct {
+	MessageId string    `json:"message_id" bson:"message_id"`
 	UserId    string    `json:"user_id" bson:"user_id"`
+	OptionIds []string  `json:"option_ids" bson:"option_ids" gorm:"-"`
 	CreatedAt time.Time `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
 }
 
+type LinkPreview struct {
+	MessageId   string    `json:"message_id" bson:"message_id"`
+	Url         string    `json:"url" bson:"url"`
+	Type        string    `json:"type,omitempty" bson:"type"`
+	Title       string    `json:"title,omitempty" bson:"title"`
+	Description string    `json:"description,omitempty" bson:"description"`
+	SiteName    string    `json:"site_name,omitempty" bson:"site_name"`
+	ImageUrl    string    `json:"image_url,omitempty" bson:"image_url"`
+	Position    int       `json:"-" bson:"position"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
+
+type Draft struct {
+	ConversationId string    `json:"conversation_id" bson:"conversation_id"`
+	UserId         string    `json:"user_id" bson:"user_id"`
+	Body           string    `json:"body" bson:"body"`
+	ParentId       string    `json:"parent_id,omitempty" bson:"parent_id"`
+	UpdatedAt      time.Time `json:"updated_at" bson:"updated_at"`
+
+	// DeviceId is echoed in draft events so the device that saved a draft can skip its own update.
+	DeviceId string `json:"device_id,omitempty" bson:"-" gorm:"-"`
+}
+
+type MessageQuote struct {
+	Id             string    `json:"_id"`
+	ConversationId string    `json:"conversation_id"`
+	UserId         string    `json:"user_id"`
+	Body           string    `json:"body"`
+	Deleted        bool      `json:"deleted"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 type User struct {
 	Id        string    `json:"_id" bson:"_id"`
 	Email     string    `json:"email" bson:"email" validate:"email, required"`
+	Handle    string    `json:"handle,omitempty" bson:"handle"`
 	Password  string    `json:"-" bson:"password" validate:"required, min=8"`
 	CreatedAt time.Time `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+
+	// LastSeenAt is only shown through presence, to the user's contacts
+	LastSeenAt *time.Time `json:"-" bson:"last_seen_at"`
+}
+
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+type Presence struct {
+	UserId     string     `json:"user_id"`
+	Status     string     `json:"status"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// PresenceSession is one connected device of a user. A user is online while any
+// of their sessions is online, and offline once every session has expired.
+type PresenceSession struct {
+	UserId    string    `json:"user_id"`
+	SessionId string    `json:"session_id"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type Typing struct {
+	ConversationId string    `json:"conversation_id"`
+	UserId         string    `json:"user_id"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type Conversation struct {
+	Id        string    `json:"_id" bson:"_id"`
+	Type      string    `json:"type" bson:"type"`
+	Name      string    `json:"name" bson:"name"`
+	DirectKey string    `json:"-" bson:"direct_key"`
+	CreatedBy string    `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+type ConversationMember struct {
+	ConversationId string    `json:"conversation_id" bson:"conversation_id"`
+	UserId         string    `json:"user_id" bson:"user_id"`
+	JoinedAt       time.Time `json:"joined_at" bson:"joined_at"`
+}
+
+type Reaction struct {
+	MessageId string    `json:"message_id" bson:"message_id"`
+	UserId    string    `json:"user_id" bson:"user_id"`
+	Emoji     string    `json:"emoji" bson:"emoji"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+type ReactionSummary struct {
+	MessageId string `json:"-" bson:"message_id"`
+	Emoji     string `json:"emoji" bson:"emoji"`
+	Count     int    `json:"count" bson:"count"`
+	Reacted   bool   `json:"reacted" bson:"reacted"`
+}
+
+type Attachment struct {
+	Id             string     `json:"_id" bson:"_id"`
+	MessageId      string     `json:"message_id" bson:"message_id"`
+	ConversationId string     `json:"conversation_id" bson:"conversation_id"`
+	UserId         string     `json:"user_id" bson:"user_id"`
+	FileName       string     `json:"file_name" bson:"file_name"`
+	MimeType       string     `json:"mime_type" bson:"mime_type"`
+	Size           int64      `json:"size" bson:"size"`
+	Checksum       string     `json:"checksum" bson:"checksum"`
+	StorageKey     string     `json:"-" bson:"storage_key"`
+	PreviewStatus  string     `json:"preview_status,omitempty" bson:"preview_status"`
+	ClaimedBy      string     `json:"-" bson:"claimed_by"`
+	ClaimedUntil   *time.Time `json:"-" bson:"claimed_until"`
+	CreatedAt      time.Time  `json:"created_at" bson:"created_at"`
+
+	Thumbnails []*Thumbnail `json:"thumbnails,omitempty" bson:"-" gorm:"-"`
+}
+
+const (
+	PreviewPending = "pending"
+	PreviewReady   = "ready"
+	PreviewFailed  = "failed"
+)
+
+type ThumbnailSize struct {
+	Name    string
+	MaxSide int
+}
+
+type Thumbnail struct {
+	AttachmentId string    `json:"attachment_id" bson:"attachment_id"`
+	Size         string    `json:"size" bson:"size"`
+	Width        int       `json:"width" bson:"width"`
+	Height       int       `json:"height" bson:"height"`
+	MimeType     string    `json:"mime_type" bson:"mime_type"`
+	StorageKey   string    `json:"-" bson:"storage_key"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+
+	Content []byte `json:"-" bson:"-" gorm:"-"`
+}
+
+type Upload struct {
+	FileName string
+	Size     int64
+	Content  io.Reader
+}
+
+type Mention struct {
+	Id             string    `json:"_id" bson:"_id"`
+	MessageId      string    `json:"message_id" bson:"message_id"`
+	ConversationId string    `json:"conversation_id" bson:"conversation_id"`
+	UserId         string    `json:"user_id" bson:"user_id"`
+	MentionedBy    string    `json:"mentioned_by" bson:"mentioned_by"`
+	Token          string    `json:"token" bson:"token"`
+	Offset         int       `json:"offset" bson:"offset"`
+	Length         int       `json:"length" bson:"length"`
+	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
+
+	Message *Message `json:"message,omitempty" bson:"-" gorm:"-"`
+}
+
+type Pin struct {
+	ConversationId string    `json:"conversation_id" bson:"conversation_id"`
+	MessageId      string    `json:"message_id" bson:"message_id"`
+	PinnedBy       string    `json:"pinned_by" bson:"pinned_by"`
+	PinnedAt       time.Time `json:"pinned_at" bson:"pinned_at"`
+
+	Message *Message `json:"message,omitempty" bson:"-" gorm:"-"`
+}
+
+type Star struct {
+	Id             string    `json:"_id" bson:"_id"`
+	UserId         string    `json:"user_id" bson:"user_id"`
+	MessageId      string    `json:"message_id" bson:"message_id"`
+	ConversationId string    `json:"conversation_id" bson:"conversation_id"`
+	Note           string    `json:"note,omitempty" bson:"note"`
+	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" bson:"updated_at"`
+
+	Message *Message `json:"message,omitempty" bson:"-" gorm:"-"`
+}
+
+const (
+	ScheduledPending   = "pending"
+	ScheduledSending   = "sending"
+	ScheduledSent      = "sent"
+	ScheduledCancelled = "cancelled"
+	ScheduledFailed    = "failed"
+)
+
+type ScheduledMessage struct {
+	Id             string     `json:"_id" bson:"_id"`
+	ConversationId string     `json:"conversation_id" bson:"conversation_id"`
+	UserId         string     `json:"user_id" bson:"user_id"`
+	Body           string     `json:"body" bson:"body"`
+	ParentId       string     `json:"parent_id,omitempty" bson:"parent_id"`
+	QuoteId        string     `json:"quote_id,omitempty" bson:"quote_id"`
+	SendAt         time.Time  `json:"send_at" bson:"send_at"`
+	Status         string     `json:"status" bson:"status"`
+	Error          string     `json:"error,omitempty" bson:"error"`
+	ClaimedBy      string     `json:"-" bson:"claimed_by"`
+	ClaimedUntil   *time.Time `json:"-" bson:"claimed_until"`
+	SentAt         *time.Time `json:"sent_at,omitempty" bson:"sent_at"`
+	CreatedAt      time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" bson:"updated_at"`
+}
+
+type MessageRevision struct {
+	Id        string    `json:"_id" bson:"_id"`
+	MessageId string    `json:"message_id" bson:"message_id"`
+	Body      string    `json:"body" bson:"body"`
+	EditorId  string    `json:"editor_id" bson:"editor_id"`
+	EditedAt  time.Time `json:"edited_at" bson:"edited_at"`
+}
+
+type ReadMarker struct {
+	ConversationId    string    `json:"conversation_id" bson:"conversation_id"`
+	UserId            string    `json:"user_id" bson:"user_id"`
+	LastReadMessageId string    `json:"last_read_message_id" bson:"last_read_message_id"`
+	LastReadAt        time.Time `json:"last_read_at" bson:"last_read_at"`
+	UpdatedAt         time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+const (
+	DeliverySent      = "sent"
+	DeliveryDelivered = "delivered"
+	DeliveryRead      = "read"
+)
+
+// DeliveryMarker is how far a user's devices have received a conversation, the
+// delivery counterpart of ReadMarker.
+type DeliveryMarker struct {
+	ConversationId         string    `json:"conversation_id" bson:"conversation_id"`
+	UserId                 string    `json:"user_id" bson:"user_id"`
+	LastDeliveredMessageId string    `json:"last_delivered_message_id" bson:"last_delivered_message_id"`
+	LastDeliveredAt        time.Time `json:"last_delivered_at" bson:"last_delivered_at"`
+	UpdatedAt              time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// DeliveryStatus sums up the receipts of a message: it is only as far along as
+// its least advanced recipient.
+type DeliveryStatus struct {
+	Status     string `json:"status"`
+	Recipients int    `json:"recipients"`
+	Delivered  int    `json:"delivered"`
+	Read       int    `json:"read"`
+}
+
+type Receipt struct {
+	MessageId string `json:"message_id"`
+	UserId    string `json:"user_id"`
+	Status    string `json:"status"`
+}
+
+type UnreadCount struct {
+	ConversationId string `json:"conversation_id"`
+	Unread         int    `json:"unread"`
+}
+
+const (
+	EventMessageCreated  = "message.created"
+	EventMessageUpdated  = "message.updated"
+	EventMessageDeleted  = "message.deleted"
+	EventMessageRestored = "message.restored"
+	EventMessageExpired  = "message.expired"
+	EventReactionAdded   = "reaction.added"
+	EventReactionRemoved = "reaction.removed"
+	EventReadUpdated     = "read.updated"
+
+	EventAttachmentPreviewed = "attachment.previewed"
+	EventMentionCreated      = "mention.created"
+	EventPollUpdated         = "poll.updated"
+	EventLinkPreviewed       = "link.previewed"
+	EventDraftUpdated        = "draft.updated"
+	EventDraftDeleted        = "draft.deleted"
+	EventPresenceUpdated     = "presence.updated"
+	EventDeliveryUpdated     = "delivery.updated"
+	EventTypingStarted       = "typing.started"
+	EventTypingStopped       = "typing.stopped"
+	EventPinAdded            = "pin.added"
+	EventPinRemoved          = "pin.removed"
+	EventStarUpdated         = "star.updated"
+	EventStarRemoved         = "star.removed"
+	EventUserRegistered      = "user.registered"
+	EventResync              = "stream.resync"
+)
+
+const (
+	WebhookPending   = "pending"
+	WebhookSending   = "sending"
+	WebhookDelivered = "delivered"
+	WebhookDead      = "dead"
+)
+
+type Webhook struct {
+	Id          string    `json:"_id" bson:"_id"`
+	Url         string    `json:"url" bson:"url"`
+	Events      []string  `json:"events" bson:"events" gorm:"-"`
+	Description string    `json:"description,omitempty" bson:"description"`
+	Secret      string    `json:"secret,omitempty" bson:"secret"`
+	CreatedBy   string    `json:"created_by" bson:"created_by"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
+
+type WebhookDelivery struct {
+	Id             string     `json:"_id" bson:"_id"`
+	WebhookId      string     `json:"webhook_id" bson:"webhook_id"`
+	Event          string     `json:"event" bson:"event"`
+	MessageId      string     `json:"message_id,omitempty" bson:"message_id"`
+	Payload        string     `json:"payload" bson:"payload"`
+	Status         string     `json:"status" bson:"status"`
+	Attempts       int        `json:"attempts" bson:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty" bson:"response_status"`
+	Error          string     `json:"error,omitempty" bson:"error"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" bson:"next_attempt_at"`
+	ClaimedBy      string     `json:"-" bson:"claimed_by"`
+	ClaimedUntil   *time.Time `json:"-" bson:"claimed_until"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" bson:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" bson:"updated_at"`
+}
+
+// IncomingWebhook is a bot identity that can post into one conversation.
+// Only a hash of its token is stored; the token itself is shown once.
+type IncomingWebhook struct {
+	Id             string     `json:"_id" bson:"_id"`
+	ConversationId string     `json:"conversation_id" bson:"conversation_id"`
+	Name           string     `json:"name" bson:"name"`
+	Token          string     `json:"token,omitempty" bson:"-" gorm:"-"`
+	TokenHash      string     `json:"-" bson:"token_hash"`
+	CreatedBy      string     `json:"created_by" bson:"created_by"`
+	CreatedAt      time.Time  `json:"created_at" bson:"created_at"`
+	RotatedAt      *time.Time `json:"rotated_at,omitempty" bson:"rotated_at"`
+}
+
+type Event struct {
+	Id             string      `json:"id,omitempty"`
+	Type           string      `json:"type"`
+	ConversationId string      `json:"conversation_id"`
+	Data           interface{} `json:"data"`
+	UserIds        []string    `json:"-"`
+	CreatedAt      time.Time   `json:"created_at"`
+
+	// Ephemeral events, like typing and presence, only matter to the clients
+	// connected right now: they get no id and are never replayed.
+	Ephemeral bool `json:"-"`
 }
@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+)
+
+type PageQuery struct {
+	Limit  int
+	Before string
+	After  string
+}
+
+type MessageFilter struct {
+	PageQuery
+	ConversationId string
+	ThreadRootId   string
+	UserId         string
+	Since          time.Time
+	Until          time.Time
+}
+
+type MessagePage struct {
+	Messages   []*Message `json:"messages"`
+	NextCursor string     `json:"next_cursor"`
+}
+
+type UserPage struct {
+	Users      []*User `json:"users"`
+	NextCursor string  `json:"next_cursor"`
+}
+
+type Cursor struct {
+	CreatedAt time.Time
+	Id        string
+}
+
+func EncodeCursor(createdAt time.Time, id string) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &Cursor{CreatedAt: createdAt, Id: parts[1]}, nil
+}
+
+// Normalize clamps the limit and rejects a query that pages both ways at once.
+func (q *PageQuery) Normalize() error {
+	if q.Before != "" && q.After != "" {
+		return errors.New("before and after cannot be used together")
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageLimit
+	}
+	if q.Limit > MaxPageLimit {
+		q.Limit = MaxPageLimit
+	}
+	return nil
+}
+
+// Cursor returns the decoded before/after cursor and whether the page goes forward in time.
+func (q PageQuery) Cursor() (*Cursor, bool, error) {
+	switch {
+	case q.After != "":
+		cursor, err := DecodeCursor(q.After)
+		return cursor, true, err
+	case q.Before != "":
+		cursor, err := DecodeCursor(q.Before)
+		return cursor, false, err
+	}
+	return nil, false, nil
+}
+
+type SearchQuery struct {
+	Query           string
+	ConversationIds []string
+	Limit           int
+	Offset          int
+}
+
+type SearchResult struct {
+	Message *Message `json:"message"`
+	Rank    float64  `json:"rank"`
+	Snippet string   `json:"snippet"`
+}
+
+type SearchPage struct {
+	Results    []*SearchResult `json:"results"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+// offsetCursorPrefix tells offset cursors apart from keyset ones, so neither
+// kind can be passed where the other is expected.
+const offsetCursorPrefix = "offset:"
+
+// Search results are ordered by rank, so they page by offset rather than by keyset.
+func EncodeOffsetCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(offsetCursorPrefix + strconv.Itoa(offset)))
+}
+
+func DecodeOffsetCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+
+	value, ok := strings.CutPrefix(string(raw), offsetCursorPrefix)
+	if !ok {
+		return 0, errors.New("invalid cursor")
+	}
+
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return offset, nil
+}
+
+type MentionPage struct {
+	Mentions   []*Mention `json:"mentions"`
+	NextCursor string     `json:"next_cursor"`
+}
+
+type StarPage struct {
+	Stars      []*Star `json:"stars"`
+	NextCursor string  `json:"next_cursor"`
+}
@@ -1,40 +1,270 @@
 package ports
 
 import (
+	"io"
+	"time"
+
 	"messenger/internal/adapters/repositories"
 	"messenger/internal/core/domain"
 )
 
 type MessangerService interface {
 	CreateMessage(userId string, message domain.Message) error
-	GetOneMessage(id string) (*domain.Message, error)
-	GetAllMessages() ([]*domain.Message, error)
+	CreateMessageWithAttachments(userId string, message domain.Message, uploads []domain.Upload) (*domain.Message, error)
+	ForwardMessage(userId, id, conversationId string) (*domain.Message, error)
+	Vote(userId, messageId string, optionIds []string) (*domain.Poll, error)
+	RetractVote(userId, messageId string) (*domain.Poll, error)
+	ClosePoll(userId, messageId string) (*domain.Poll, error)
+	SaveDraft(userId string, draft domain.Draft) (*domain.Draft, error)
+	GetDraft(userId, conversationId string) (*domain.Draft, error)
+	GetDrafts(userId string) ([]*domain.Draft, error)
+	DeleteDraft(userId, conversationId, deviceId string) error
+	MarkDelivered(userId, conversationId, messageId string) error
+	GetReceipts(userId, messageId string) ([]*domain.Receipt, error)
+	CreateIncomingWebhook(userId, conversationId, name string) (*domain.IncomingWebhook, error)
+	GetIncomingWebhooks(userId, conversationId string) ([]*domain.IncomingWebhook, error)
+	RotateIncomingWebhook(userId, conversationId, id string) (*domain.IncomingWebhook, error)
+	DeleteIncomingWebhook(userId, conversationId, id string) error
+	PostIncoming(token string, message domain.Message) (*domain.Message, error)
+	GetAttachment(userId, id string) (*domain.Attachment, io.ReadCloser, error)
+	GetThumbnail(userId, attachmentId, size string) (*domain.Thumbnail, io.ReadCloser, error)
+	GetOneMessage(userId, id string) (*domain.Message, error)
+	GetAllMessages(userId string, filter domain.MessageFilter) (*domain.MessagePage, error)
+	SearchMessages(userId, conversationId, q, cursor string, limit int) (*domain.SearchPage, error)
+	GetReplies(userId, id string, query domain.PageQuery) (*domain.MessagePage, error)
+	AddReaction(userId, messageId, emoji string) error
+	RemoveReaction(userId, messageId, emoji string) error
 	UpdateMessage(id, body, user_id string) (*domain.Message, error)
-	DeleteMessage(id string) error
+	GetHistory(userId, id string) ([]*domain.MessageRevision, error)
+	GetMentions(userId string, query domain.PageQuery) (*domain.MentionPage, error)
+	PinMessage(userId, messageId string) (*domain.Pin, error)
+	UnpinMessage(userId, messageId string) error
+	GetPins(userId, conversationId string) ([]*domain.Pin, error)
+	StarMessage(userId, messageId, note string) (*domain.Star, error)
+	UnstarMessage(userId, messageId string) error
+	GetStarred(userId string, query domain.PageQuery) (*domain.StarPage, error)
+	ScheduleMessage(userId string, message domain.Message) (*domain.ScheduledMessage, error)
+	GetScheduledMessages(userId, conversationId string) ([]*domain.ScheduledMessage, error)
+	UpdateScheduledMessage(userId, id, body string, sendAt *time.Time) (*domain.ScheduledMessage, error)
+	CancelScheduledMessage(userId, id string) error
+	DeleteMessage(id, user_id string) error
+	RestoreMessage(id, user_id string) (*domain.Message, error)
+	PurgeDeletedMessages() (int, error)
+	ExpireMessages() (int, error)
 }
 
 type UserService interface {
 	RegisterUser(user domain.User) error
 	GetOneUser(id string) (*domain.User, error)
-	GetAllUsers() ([]*domain.User, error)
+	GetAllUsers(query domain.PageQuery) (*domain.UserPage, error)
 	LoginUser(email, password string) (*repositories.LoginResponse, error)
 	UpdateUser(id, email, password string) (*domain.User, error)
 	DeleteUser(id string) error
 }
 
+type ConversationService interface {
+	CreateDirectConversation(userId, otherUserId string) (*domain.Conversation, error)
+	CreateGroupConversation(userId, name string, memberIds []string) (*domain.Conversation, error)
+	GetOneConversation(userId, id string) (*domain.Conversation, error)
+	GetUserConversations(userId string) ([]*domain.Conversation, error)
+	GetMembers(userId, conversationId string) ([]*domain.ConversationMember, error)
+	AddMember(userId, conversationId, memberId string) error
+	RemoveMember(userId, conversationId, memberId string) error
+}
+
+type ReadService interface {
+	MarkRead(userId, conversationId, messageId string) error
+	GetReadMarkers(userId, conversationId string) ([]*domain.ReadMarker, error)
+	GetUnreadCounts(userId string) ([]*domain.UnreadCount, error)
+}
+
 type MessangerRepository interface {
 	CreateMessage(message domain.Message) error
 	GetOneMessage(id string) (*domain.Message, error)
-	GetAllMessages() ([]*domain.Message, error)
-	UpdateMessage(id, body, user_id string) (*domain.Message, error)
-	DeleteMessage(id, user_id string) error
+	GetMessages(ids []string) ([]*domain.Message, error)
+	GetAllMessages(filter domain.MessageFilter) (*domain.MessagePage, error)
+	SearchMessages(query domain.SearchQuery) (*domain.SearchPage, error)
+	CountMessagesSince(excludeUserId string, since map[string]time.Time) (map[string]int, error)
+	UpdateMessage(id, body, user_id string, editedAt time.Time) (*domain.Message, error)
+	DeleteMessage(id, user_id string, deletedAt time.Time) error
+	RestoreMessage(id, user_id string, deletedSince time.Time) error
+	PurgeDeletedMessages(deletedBefore time.Time) ([]string, error)
+	StartReadExpiry(conversationId, readerId string, readUntil, readAt time.Time) error
+	ExpireMessages(now time.Time) ([]*domain.Message, error)
 }
 
 type UserRepository interface {
 	RegisterUser(user domain.User) error
 	GetOneUser(id string) (*domain.User, error)
-	GetAllUsers() ([]*domain.User, error)
+	GetAllUsers(query domain.PageQuery) (*domain.UserPage, error)
 	LoginUser(email, password string) (*repositories.LoginResponse, error)
 	UpdateUser(id, email, password string) (*domain.User, error)
 	DeleteUser(id string) error
+	GetUsersByEmails(emails []string) ([]*domain.User, error)
+	GetUsersByHandles(handles []string) ([]*domain.User, error)
+	GetUsers(ids []string) ([]*domain.User, error)
+	SetLastSeen(id string, lastSeenAt time.Time) error
+}
+
+type ConversationRepository interface {
+	CreateConversation(conversation domain.Conversation, memberIds []string) error
+	GetOneConversation(id string) (*domain.Conversation, error)
+	GetDirectConversation(directKey string) (*domain.Conversation, error)
+	GetUserConversations(userId string) ([]*domain.Conversation, error)
+	AddMember(member domain.ConversationMember) error
+	RemoveMember(conversationId, userId string) error
+	GetMembers(conversationId string) ([]*domain.ConversationMember, error)
+	IsMember(conversationId, userId string) (bool, error)
+	GetContactIds(userId string) ([]string, error)
+}
+
+type ReactionRepository interface {
+	AddReaction(reaction domain.Reaction) error
+	RemoveReaction(messageId, userId, emoji string) error
+	GetReactionSummaries(messageIds []string, userId string) ([]*domain.ReactionSummary, error)
+	DeleteReactions(messageIds []string) error
+}
+
+type RevisionRepository interface {
+	AddRevision(revision domain.MessageRevision) error
+	GetRevisions(messageId string) ([]*domain.MessageRevision, error)
+	DeleteRevision(id string) error
+	DeleteRevisions(messageIds []string) error
+}
+
+type AttachmentRepository interface {
+	AddAttachments(attachments []*domain.Attachment) error
+	GetAttachment(id string) (*domain.Attachment, error)
+	GetAttachments(messageIds []string) ([]*domain.Attachment, error)
+	DeleteAttachments(messageIds []string) error
+	ClaimPendingPreviews(now time.Time, owner string, lease time.Duration, limit int) ([]*domain.Attachment, error)
+	SetPreviewStatus(id, status string) error
+	AddThumbnails(thumbnails []*domain.Thumbnail) error
+	GetThumbnails(attachmentIds []string) ([]*domain.Thumbnail, error)
+	DeleteThumbnails(attachmentIds []string) error
+}
+
+type Thumbnailer interface {
+	Thumbnails(src io.Reader, sizes []domain.ThumbnailSize) ([]*domain.Thumbnail, error)
+}
+
+type BlobStorage interface {
+	Put(key string, body io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+type MentionRepository interface {
+	SetMentions(messageId string, mentions []*domain.Mention) error
+	GetMentions(messageIds []string) ([]*domain.Mention, error)
+	GetUserMentions(userId string, query domain.PageQuery) (*domain.MentionPage, error)
+	DeleteMentions(messageIds []string) error
+}
+
+type PinRepository interface {
+	AddPin(pin domain.Pin) error
+	RemovePin(conversationId, messageId string) error
+	GetPins(conversationId string) ([]*domain.Pin, error)
+	DeletePins(messageIds []string) error
+}
+
+type StarRepository interface {
+	SetStar(star domain.Star) (*domain.Star, error)
+	RemoveStar(userId, messageId string) error
+	GetStars(userId string, query domain.PageQuery) (*domain.StarPage, error)
+	DeleteStars(messageIds []string) error
+}
+
+type ScheduledMessageRepository interface {
+	AddScheduledMessage(scheduled domain.ScheduledMessage) error
+	GetScheduledMessages(userId, conversationId string) ([]*domain.ScheduledMessage, error)
+	UpdateScheduledMessage(id, userId, body string, sendAt, updatedAt time.Time) (*domain.ScheduledMessage, error)
+	CancelScheduledMessage(id, userId string, cancelledAt time.Time) error
+	ClaimDueMessages(now time.Time, owner string, lease time.Duration, limit int) ([]*domain.ScheduledMessage, error)
+	FinishScheduledMessage(id, owner, status, errorMessage string, finishedAt time.Time) error
+}
+
+type PollRepository interface {
+	CreatePoll(poll domain.Poll) error
+	GetPolls(messageIds []string) ([]*domain.Poll, error)
+	ClosePoll(messageId string, closedAt time.Time) error
+	CastVote(vote domain.PollVote) error
+	RemoveVote(messageId, userId string, now time.Time) error
+	GetVotes(messageIds []string) ([]*domain.PollVote, error)
+	DeletePolls(messageIds []string) error
+}
+
+type LinkPreviewRepository interface {
+	SetLinkPreviews(messageId string, previews []*domain.LinkPreview) error
+	GetLinkPreviews(messageIds []string) ([]*domain.LinkPreview, error)
+	DeleteLinkPreviews(messageIds []string) error
+}
+
+type Unfurler interface {
+	Unfurl(url string) (*domain.LinkPreview, error)
+}
+
+type DraftRepository interface {
+	SetDraft(draft domain.Draft) error
+	GetDraft(userId, conversationId string) (*domain.Draft, error)
+	GetDrafts(userId string) ([]*domain.Draft, error)
+	DeleteDraft(userId, conversationId string) (bool, error)
+	ClearDraft(userId, conversationId, parentId string) (bool, error)
+}
+
+// PresenceStore holds short-lived presence and typing state. Entries carry their own
+// expiry, and Expire* removes and returns the ones that have passed so the caller
+// can announce them.
+type PresenceStore interface {
+	SetSession(session domain.PresenceSession) error
+	RemoveSession(userId, sessionId string) error
+	GetSessions(userIds []string, now time.Time) ([]*domain.PresenceSession, error)
+	ExpireSessions(now time.Time) ([]*domain.PresenceSession, error)
+	SetTyping(typing domain.Typing) (bool, error)
+	RemoveTyping(conversationId, userId string) (bool, error)
+	GetTyping(conversationId string, now time.Time) ([]*domain.Typing, error)
+	ExpireTyping(now time.Time) ([]*domain.Typing, error)
+}
+
+type ReceiptRepository interface {
+	SetDeliveryMarker(marker domain.DeliveryMarker) (bool, error)
+	GetDeliveryMarkers(conversationId string) ([]*domain.DeliveryMarker, error)
+}
+
+type ReadMarkerRepository interface {
+	SetReadMarker(marker domain.ReadMarker) (bool, error)
+	GetReadMarkers(conversationId string) ([]*domain.ReadMarker, error)
+	GetUserReadMarkers(userId string) ([]*domain.ReadMarker, error)
+}
+
+type WebhookRepository interface {
+	AddWebhook(webhook domain.Webhook) error
+	GetWebhooks() ([]*domain.Webhook, error)
+	GetWebhook(id string) (*domain.Webhook, error)
+	DeleteWebhook(id string) error
+	AddDeliveries(deliveries []domain.WebhookDelivery) error
+	GetDeliveries(webhookId, status string, limit int) ([]*domain.WebhookDelivery, error)
+	ClaimDueDeliveries(now time.Time, owner string, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error)
+	FinishDelivery(delivery domain.WebhookDelivery, owner string) error
+	RetryDelivery(id, webhookId string, now time.Time) error
+	PruneDeliveries(before time.Time) (int, error)
+	DeleteMessageDeliveries(messageIds []string) error
+}
+
+type WebhookSender interface {
+	CheckUrl(url string) error
+	Send(url string, headers map[string]string, body []byte) (int, error)
+}
+
+type IncomingWebhookRepository interface {
+	AddIncomingWebhook(hook domain.IncomingWebhook) error
+	GetIncomingWebhook(id string) (*domain.IncomingWebhook, error)
+	GetIncomingWebhookByToken(tokenHash string) (*domain.IncomingWebhook, error)
+	GetIncomingWebhooks(conversationId string) ([]*domain.IncomingWebhook, error)
+	RotateIncomingWebhook(id, tokenHash string, rotatedAt time.Time) error
+	DeleteIncomingWebhook(id string) error
+}
+
+type EventPublisher interface {
+	Publish(event domain.Event)
 }
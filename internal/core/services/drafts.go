@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"messenger/internal/core/domain"
+)
+
+func (m *MessangerService) SaveDraft(userId string, draft domain.Draft) (*domain.Draft, error) {
+	if err := checkMember(m.conversationRepo, draft.ConversationId, userId); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(draft.Body) == "" {
+		return nil, errors.New("draft needs a body")
+	}
+
+	if draft.ParentId != "" {
+		parent, err := m.repo.GetOneMessage(draft.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		if parent.ConversationId != draft.ConversationId {
+			return nil, errors.New("reply must be in the same conversation as its parent")
+		}
+	}
+
+	draft.UserId = userId
+	draft.UpdatedAt = time.Now().UTC()
+	if err := m.draftRepo.SetDraft(draft); err != nil {
+		return nil, err
+	}
+
+	m.publishToUser(domain.EventDraftUpdated, draft.ConversationId, userId, draft)
+	return &draft, nil
+}
+
+func (m *MessangerService) GetDraft(userId, conversationId string) (*domain.Draft, error) {
+	if err := checkMember(m.conversationRepo, conversationId, userId); err != nil {
+		return nil, err
+	}
+	return m.draftRepo.GetDraft(userId, conversationId)
+}
+
+func (m *MessangerService) GetDrafts(userId string) ([]*domain.Draft, error) {
+	return m.draftRepo.GetDrafts(userId)
+}
+
+func (m *MessangerService) DeleteDraft(userId, conversationId, deviceId string) error {
+	if err := checkMember(m.conversationRepo, conversationId, userId); err != nil {
+		return err
+	}
+
+	deleted, err := m.draftRepo.DeleteDraft(userId, conversationId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("draft not found")
+	}
+
+	m.publishDraftDeleted(userId, conversationId, deviceId)
+	return nil
+}
+
+// clearDraft drops the draft of a conversation once its message has been sent,
+// unless the draft is for another thread than the message.
+func (m *MessangerService) clearDraft(userId, conversationId, parentId string) {
+	deleted, err := m.draftRepo.ClearDraft(userId, conversationId, parentId)
+	if err != nil {
+		log.Printf("clear draft of %s in %s: %v", userId, conversationId, err)
+		return
+	}
+	if deleted {
+		m.publishDraftDeleted(userId, conversationId, "")
+	}
+}
+
+func (m *MessangerService) publishDraftDeleted(userId, conversationId, deviceId string) {
+	m.publishToUser(domain.EventDraftDeleted, conversationId, userId, domain.Draft{
+		ConversationId: conversationId,
+		UserId:         userId,
+		UpdatedAt:      time.Now().UTC(),
+		DeviceId:       deviceId,
+	})
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"time"
+
+	"messenger/internal/core/domain"
+	"messenger/internal/core/ports"
+)
+
+func publishToConversation(events ports.EventPublisher, conversationRepo ports.ConversationRepository, eventType, conversationId string, data interface{}) {
+	publishConversationEvent(events, conversationRepo, domain.Event{Type: eventType, ConversationId: conversationId, Data: data})
+}
+
+// publishEphemeralToConversation sends an event that is not kept for replay.
+func publishEphemeralToConversation(events ports.EventPublisher, conversationRepo ports.ConversationRepository, eventType, conversationId string, data interface{}) {
+	publishConversationEvent(events, conversationRepo, domain.Event{Type: eventType, ConversationId: conversationId, Data: data, Ephemeral: true})
+}
+
+func publishConversationEvent(events ports.EventPublisher, conversationRepo ports.ConversationRepository, event domain.Event) {
+	if events == nil {
+		return
+	}
+
+	conversationId := event.ConversationId
+
+	members, err := conversationRepo.GetMembers(conversationId)
+	if err != nil {
+		return
+	}
+
+	userIds := make([]string, 0, len(members))
+	for _, member := range members {
+		userIds = append(userIds, member.UserId)
+	}
+
+	event.UserIds = userIds
+	event.CreatedAt = time.Now().UTC()
+	events.Publish(event)
+}
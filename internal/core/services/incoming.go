@@ -0,0 +1,209 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"messenger/internal/core/domain"
+)
+
+const (
+	DefaultIncomingRateLimit  = 20
+	DefaultIncomingRateWindow = time.Minute
+
+	maxBotNameLength = 80
+)
+
+var ErrRateLimited = errors.New("too many requests, try again later")
+
+// rateLimiter allows a fixed number of calls per key in each window. It is kept
+// in memory, so with several instances every instance applies the limit alone.
+type rateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+func (r *rateLimiter) allow(key string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.windows[key]
+	if !ok || now.Sub(current.start) >= r.window {
+		r.sweep(now)
+		current = &rateWindow{start: now}
+		r.windows[key] = current
+	}
+
+	if current.count >= r.limit {
+		return false
+	}
+	current.count++
+	return true
+}
+
+func (r *rateLimiter) sweep(now time.Time) {
+	for key, window := range r.windows {
+		if now.Sub(window.start) >= r.window {
+			delete(r.windows, key)
+		}
+	}
+}
+
+func (m *MessangerService) SetIncomingRateLimit(limit int, window time.Duration) {
+	m.incomingLimiter = newRateLimiter(limit, window)
+}
+
+func (m *MessangerService) CreateIncomingWebhook(userId, conversationId, name string) (*domain.IncomingWebhook, error) {
+	if err := checkMember(m.conversationRepo, conversationId, userId); err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxBotNameLength {
+		return nil, errors.New("name must be 1-80 characters")
+	}
+
+	token, tokenHash, err := newIncomingToken()
+	if err != nil {
+		return nil, err
+	}
+
+	hook := domain.IncomingWebhook{
+		Id:             uuid.New().String(),
+		ConversationId: conversationId,
+		Name:           name,
+		TokenHash:      tokenHash,
+		CreatedBy:      userId,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if err := m.incomingRepo.AddIncomingWebhook(hook); err != nil {
+		return nil, err
+	}
+
+	hook.Token = token
+	return &hook, nil
+}
+
+func (m *MessangerService) GetIncomingWebhooks(userId, conversationId string) ([]*domain.IncomingWebhook, error) {
+	if err := checkMember(m.conversationRepo, conversationId, userId); err != nil {
+		return nil, err
+	}
+	return m.incomingRepo.GetIncomingWebhooks(conversationId)
+}
+
+// RotateIncomingWebhook replaces the token; the old one stops working at once.
+func (m *MessangerService) RotateIncomingWebhook(userId, conversationId, id string) (*domain.IncomingWebhook, error) {
+	hook, err := m.getIncomingWebhook(userId, conversationId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	token, tokenHash, err := newIncomingToken()
+	if err != nil {
+		return nil, err
+	}
+
+	rotatedAt := time.Now().UTC()
+	if err := m.incomingRepo.RotateIncomingWebhook(hook.Id, tokenHash, rotatedAt); err != nil {
+		return nil, err
+	}
+
+	hook.Token = token
+	hook.RotatedAt = &rotatedAt
+	return hook, nil
+}
+
+func (m *MessangerService) DeleteIncomingWebhook(userId, conversationId, id string) error {
+	hook, err := m.getIncomingWebhook(userId, conversationId, id)
+	if err != nil {
+		return err
+	}
+	return m.incomingRepo.DeleteIncomingWebhook(hook.Id)
+}
+
+// PostIncoming posts a message as the bot of the webhook the token belongs to.
+func (m *MessangerService) PostIncoming(token string, message domain.Message) (*domain.Message, error) {
+	hook, err := m.incomingRepo.GetIncomingWebhookByToken(hashIncomingToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if m.incomingLimiter != nil && !m.incomingLimiter.allow(hook.Id, time.Now()) {
+		return nil, ErrRateLimited
+	}
+
+	return m.createMessage(hook.Id, uuid.New().String(), domain.Message{
+		ConversationId: hook.ConversationId,
+		Body:           message.Body,
+		ParentId:       message.ParentId,
+		BotName:        hook.Name,
+	}, nil)
+}
+
+func (m *MessangerService) getIncomingWebhook(userId, conversationId, id string) (*domain.IncomingWebhook, error) {
+	if err := checkMember(m.conversationRepo, conversationId, userId); err != nil {
+		return nil, err
+	}
+
+	hook, err := m.incomingRepo.GetIncomingWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if hook.ConversationId != conversationId {
+		return nil, errors.New("incoming webhook not found")
+	}
+	return hook, nil
+}
+
+// checkAuthor lets members post, and the bot of an incoming webhook post into
+// the one conversation its webhook belongs to.
+func (m *MessangerService) checkAuthor(conversationId, userId string) error {
+	err := checkMember(m.conversationRepo, conversationId, userId)
+	if !errors.Is(err, ErrNotConversationMember) {
+		return err
+	}
+
+	hook, hookErr := m.incomingRepo.GetIncomingWebhook(userId)
+	if hookErr != nil || hook.ConversationId != conversationId {
+		return err
+	}
+	return nil
+}
+
+func newIncomingToken() (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	token := hex.EncodeToString(secret)
+	return token, hashIncomingToken(token), nil
+}
+
+func hashIncomingToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The supported subset: **bold**, *italic* (or _italic_), `code`, fenced code
+// blocks, [links](https://...), bare links and flat "-" or "1." lists. All text
+// is escaped and only the tags below are ever produced, so HTML in a body is
+// shown as text instead of being interpreted.
+var (
+	fencePattern   = regexp.MustCompile("^\\s*```\\s*([A-Za-z0-9_+\\-]*)\\s*$")
+	bulletPattern  = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^\s*(\d{1,9})[.)]\s+(.*)$`)
+	barePattern    = regexp.MustCompile("^" + urlPattern.String())
+)
+
+const linkAttributes = ` rel="nofollow noopener noreferrer" target="_blank"`
+
+func renderMarkdown(body string) string {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+
+	var out strings.Builder
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		out.WriteString("<p>")
+		for i, line := range paragraph {
+			if i > 0 {
+				out.WriteString("<br>")
+			}
+			renderInline(&out, strings.TrimSpace(line), true)
+		}
+		out.WriteString("</p>")
+		paragraph = nil
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		if match := fencePattern.FindStringSubmatch(line); match != nil {
+			flush()
+			end := i + 1
+			for end < len(lines) && strings.TrimSpace(lines[end]) != "```" {
+				end++
+			}
+
+			out.WriteString("<pre><code")
+			if match[1] != "" {
+				out.WriteString(` class="language-` + html.EscapeString(strings.ToLower(match[1])) + `"`)
+			}
+			out.WriteString(">")
+			out.WriteString(html.EscapeString(strings.Join(lines[i+1:min(end, len(lines))], "\n")))
+			out.WriteString("</code></pre>")
+			i = end + 1
+			continue
+		}
+
+		if bulletPattern.MatchString(line) {
+			flush()
+			out.WriteString("<ul>")
+			for ; i < len(lines) && bulletPattern.MatchString(lines[i]); i++ {
+				out.WriteString("<li>")
+				renderInline(&out, strings.TrimSpace(bulletPattern.FindStringSubmatch(lines[i])[1]), true)
+				out.WriteString("</li>")
+			}
+			out.WriteString("</ul>")
+			continue
+		}
+
+		if match := orderedPattern.FindStringSubmatch(line); match != nil {
+			flush()
+			out.WriteString("<ol")
+			if start, _ := strconv.Atoi(match[1]); start != 1 {
+				out.WriteString(` start="` + strconv.Itoa(start) + `"`)
+			}
+			out.WriteString(">")
+			for ; i < len(lines) && orderedPattern.MatchString(lines[i]); i++ {
+				out.WriteString("<li>")
+				renderInline(&out, strings.TrimSpace(orderedPattern.FindStringSubmatch(lines[i])[2]), true)
+				out.WriteString("</li>")
+			}
+			out.WriteString("</ol>")
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+		} else {
+			paragraph = append(paragraph, line)
+		}
+		i++
+	}
+	flush()
+	return out.String()
+}
+
+// renderInline writes the inline formatting of text. Links are not allowed
+// inside link text, so links is false while rendering it.
+func renderInline(out *strings.Builder, text string, links bool) {
+	newInlineParser(text).render(out, 0, len(text), links)
+}
+
+// inlineParser matches code spans, brackets, emphasis and bare links of a line
+// in a single pass up front, so rendering never scans ahead for a closer and
+// stays linear in the length of the line however the delimiters are nested.
+type inlineParser struct {
+	text string
+	// run is the length of the backtick or emphasis run starting at an index
+	run []int
+	// match is the closer of the code span, bracket or emphasis opened at an index
+	match []int
+	// target is the ")" ending the link target after the "]" at an index
+	target []int
+}
+
+type emphasisRun struct {
+	pos, length   int
+	opens, closes bool
+	key           int
+	next          int
+}
+
+func newInlineParser(text string) *inlineParser {
+	p := &inlineParser{
+		text:   text,
+		run:    make([]int, len(text)),
+		match:  make([]int, len(text)),
+		target: make([]int, len(text)),
+	}
+	for i := range p.match {
+		p.match[i] = -1
+		p.target[i] = -1
+	}
+
+	codeClose := p.codeClosers()
+
+	var brackets, closed, parens []int
+	var runs []emphasisRun
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isMarkdownPunct(text[i+1]):
+			i += 2
+			continue
+
+		case c == '`':
+			run := delimiterRun(text, i)
+			p.run[i] = run
+			if close, ok := codeClose[i]; ok {
+				p.match[i] = close
+				i = close + run
+				continue
+			}
+			i += run
+			continue
+
+		case c == '[':
+			brackets = append(brackets, i)
+
+		case c == ']':
+			if len(brackets) > 0 {
+				p.match[brackets[len(brackets)-1]] = i
+				brackets = brackets[:len(brackets)-1]
+				closed = append(closed, i)
+			}
+
+		case c == ')':
+			parens = append(parens, i)
+
+		case c == '*' || c == '_':
+			run := delimiterRun(text, i)
+			p.run[i] = run
+			if run <= 2 {
+				runs = append(runs, emphasisRun{
+					pos:    i,
+					length: run,
+					opens:  i+run < len(text) && text[i+run] != ' ' && !(c == '_' && i > 0 && isWordByte(text[i-1])),
+					closes: i > 0 && text[i-1] != ' ' && !(c == '_' && i+run < len(text) && isWordByte(text[i+run])),
+					key:    int(c)<<2 | run,
+				})
+			}
+			i += run
+			continue
+		}
+		i++
+	}
+
+	// closed and parens are both in text order, so one walk pairs each "](" with
+	// the first ")" after it
+	next := 0
+	for _, i := range closed {
+		if i+1 >= len(text) || text[i+1] != '(' {
+			continue
+		}
+		for next < len(parens) && parens[next] <= i+1 {
+			next++
+		}
+		if next < len(parens) {
+			p.target[i] = parens[next]
+		}
+	}
+
+	// an emphasis closes at the first later run of the same delimiter and length
+	// that can close it, leaving at least one character in between
+	nearest := make(map[int]int)
+	for k := len(runs) - 1; k >= 0; k-- {
+		run := &runs[k]
+		if run.opens {
+			if close, ok := nearest[run.key]; ok {
+				if runs[close].pos == run.pos+run.length {
+					close = runs[close].next
+				}
+				if close >= 0 {
+					p.match[run.pos] = runs[close].pos
+				}
+			}
+		}
+		if run.closes {
+			run.next = -1
+			if close, ok := nearest[run.key]; ok {
+				run.next = close
+			}
+			nearest[run.key] = k
+		}
+	}
+	return p
+}
+
+// codeClosers pairs each run of backticks with the next run of the same length.
+func (p *inlineParser) codeClosers() map[int]int {
+	closers := make(map[int]int)
+	later := make(map[int]int)
+	for i := len(p.text) - 1; i >= 0; i-- {
+		if p.text[i] != '`' || (i > 0 && p.text[i-1] == '`') {
+			continue
+		}
+		run := delimiterRun(p.text, i)
+		if close, ok := later[run]; ok {
+			closers[i] = close
+		}
+		later[run] = i
+	}
+	return closers
+}
+
+func (p *inlineParser) render(out *strings.Builder, from, to int, links bool) {
+	text := p.text
+	// a bare link that turned out unsafe is not searched again for links, which
+	// keeps a chain of them from being matched over and over
+	noLinksUntil := from
+	for i := from; i < to; {
+		c := text[i]
+
+		switch {
+		case c == '\\' && i+1 < to && isMarkdownPunct(text[i+1]):
+			out.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`' && p.run[i] > 0:
+			run := p.run[i]
+			if end := p.match[i]; end >= 0 && end+run <= to {
+				code := text[i+run : end]
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				out.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i = end + run
+				continue
+			}
+			out.WriteString(text[i:min(i+run, to)])
+			i += run
+			continue
+
+		case c == '[' && links:
+			if end := p.match[i]; end >= 0 && end < to && p.target[end] >= 0 && p.target[end] < to {
+				if target := strings.TrimSpace(text[end+2 : p.target[end]]); safeLink(target) {
+					out.WriteString(`<a href="` + html.EscapeString(target) + `"` + linkAttributes + ">")
+					p.render(out, i+1, end, false)
+					out.WriteString("</a>")
+					i = p.target[end] + 1
+					continue
+				}
+			}
+
+		case (c == '*' || c == '_') && p.run[i] > 0:
+			run := p.run[i]
+			if end := p.match[i]; end >= 0 && end+run <= to {
+				tag := "em"
+				if run == 2 {
+					tag = "strong"
+				}
+				out.WriteString("<" + tag + ">")
+				p.render(out, i+run, end, links)
+				out.WriteString("</" + tag + ">")
+				i = end + run
+				continue
+			}
+			out.WriteString(text[i:min(i+run, to)])
+			i += run
+			continue
+
+		case c == 'h' && links && i >= noLinksUntil && (i == from || !isWordByte(text[i-1])):
+			if link := barePattern.FindString(text[i:to]); link != "" {
+				noLinksUntil = i + len(link)
+				link = trimLink(link)
+				if safeLink(link) {
+					out.WriteString(`<a href="` + html.EscapeString(link) + `"` + linkAttributes + ">" + html.EscapeString(link) + "</a>")
+					i += len(link)
+					continue
+				}
+			}
+		}
+
+		out.WriteString(html.EscapeString(text[i : i+1]))
+		i++
+	}
+}
+
+func delimiterRun(text string, start int) int {
+	run := 0
+	for start+run < len(text) && text[start+run] == text[start] {
+		run++
+	}
+	return run
+}
+
+func safeLink(link string) bool {
+	if strings.ContainsAny(link, " \t\n\"'<>`") {
+		return false
+	}
+
+	target, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(target.Scheme) {
+	case "http", "https":
+		return target.Host != ""
+	case "mailto":
+		return target.Opaque != ""
+	}
+	return false
+}
+
+// trimLink drops the punctuation that usually trails a link in a sentence.
+func trimLink(link string) string {
+	link = strings.TrimRight(link, ".,;:!?'\"*_")
+	opened, closed := strings.Count(link, "("), strings.Count(link, ")")
+	for ; strings.HasSuffix(link, ")") && opened < closed; closed-- {
+		link = strings.TrimSuffix(link, ")")
+	}
+	return link
+}
+
+func isMarkdownPunct(c byte) bool {
+	return strings.IndexByte("\\`*_{}[]()#+-.!|<>~", c) >= 0
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
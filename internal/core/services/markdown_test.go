@@ -0,0 +1,172 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		body string
+		html string
+	}{
+		{"plain", "<p>plain</p>"},
+		{"**bold** and *italic* and _also_", "<p><strong>bold</strong> and <em>italic</em> and <em>also</em></p>"},
+		{"snake_case_name", "<p>snake_case_name</p>"},
+		{"`a <b>` and ``x ` y``", "<p><code>a &lt;b&gt;</code> and <code>x ` y</code></p>"},
+		{`\*not italic\*`, "<p>*not italic*</p>"},
+		{"*unclosed and **also", "<p>*unclosed and **also</p>"},
+		{
+			"[docs](https://example.com/a?b=1&c=2)",
+			`<p><a href="https://example.com/a?b=1&amp;c=2"` + linkAttributes + `>docs</a></p>`,
+		},
+		{
+			"see https://example.com/x.",
+			`<p>see <a href="https://example.com/x"` + linkAttributes + `>https://example.com/x</a>.</p>`,
+		},
+		{
+			"*see https://example.com*",
+			`<p><em>see <a href="https://example.com"` + linkAttributes + `>https://example.com</a></em></p>`,
+		},
+		{
+			"[**bold** https://example.com](https://example.com)",
+			`<p><a href="https://example.com"` + linkAttributes + `><strong>bold</strong> https://example.com</a></p>`,
+		},
+		{"- one\n- two", "<ul><li>one</li><li>two</li></ul>"},
+		{"3. three\n4. four", `<ol start="3"><li>three</li><li>four</li></ol>`},
+		{"```go\nif a < b {}\n```", `<pre><code class="language-go">if a &lt; b {}</code></pre>`},
+	}
+
+	for _, test := range tests {
+		if got := renderMarkdown(test.body); got != test.html {
+			t.Errorf("renderMarkdown(%q)\n got %s\nwant %s", test.body, got, test.html)
+		}
+	}
+}
+
+func TestRenderMarkdownRefusesUnsafeLinks(t *testing.T) {
+	bodies := []string{
+		"[click](javascript:alert(1))",
+		"[click](JavaScript:alert(document.cookie))",
+		"[click]( javascript:alert(1) )",
+		"[click](data:text/html;base64,PHNjcmlwdD4=)",
+		"[click](vbscript:msgbox)",
+		"[click](//evil.example)",
+		"javascript:alert(1)",
+	}
+
+	for _, body := range bodies {
+		if got := renderMarkdown(body); strings.Contains(got, "<a ") {
+			t.Errorf("renderMarkdown(%q) = %s, want no link", body, got)
+		}
+	}
+}
+
+func TestRenderMarkdownEscapesHtml(t *testing.T) {
+	tests := map[string]string{
+		"<script>alert(1)</script>":           "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>",
+		"<img src=x onerror=alert(1)>":        "<p>&lt;img src=x onerror=alert(1)&gt;</p>",
+		"**<script>**":                        "<p><strong>&lt;script&gt;</strong></p>",
+		"```\n</code><script>alert(1)\n```":   "<pre><code>&lt;/code&gt;&lt;script&gt;alert(1)</code></pre>",
+		"```\"><script>\nx\n```":              "<p>```&#34;&gt;&lt;script&gt;<br>x</p><pre><code></code></pre>",
+		"[<script>x</script>](https://a.io)":  `<p><a href="https://a.io"` + linkAttributes + `>&lt;script&gt;x&lt;/script&gt;</a></p>`,
+		"\\<script\\>":                        "<p>&lt;script&gt;</p>",
+		"`<script>`":                          "<p><code>&lt;script&gt;</code></p>",
+		"- <b>item</b>":                       "<ul><li>&lt;b&gt;item&lt;/b&gt;</li></ul>",
+		"&lt;script&gt;":                      "<p>&amp;lt;script&amp;gt;</p>",
+		"[x](https://a.io/\"onmouseover=\"x)": "<p>[x](<a href=\"https://a.io/\"" + linkAttributes + ">https://a.io/</a>&#34;onmouseover=&#34;x)</p>",
+	}
+
+	for body, want := range tests {
+		if got := renderMarkdown(body); got != want {
+			t.Errorf("renderMarkdown(%q)\n got %s\nwant %s", body, got, want)
+		}
+		checkRenderedTags(t, body, renderMarkdown(body))
+	}
+}
+
+func TestRenderMarkdownKeepsAttributesClosed(t *testing.T) {
+	bodies := []string{
+		`[x](https://a.io/" onclick="alert(1))`,
+		`[x](https://a.io/'onclick='alert(1))`,
+		"[x](https://a.io/`onclick=alert(1))",
+		`[x](https://a.io/><script>alert(1)</script>)`,
+		`https://a.io/"onclick="alert(1)`,
+		`https://a.io/'><script>alert(1)</script>`,
+		`[x](mailto:a@b.io?subject="><script>)`,
+	}
+
+	for _, body := range bodies {
+		checkRenderedTags(t, body, renderMarkdown(body))
+	}
+}
+
+// checkRenderedTags fails unless rendered only holds the tags and attributes the
+// renderer produces, with links to http, https or mailto targets.
+func checkRenderedTags(t *testing.T, body, rendered string) {
+	t.Helper()
+
+	allowed := map[string][]string{
+		"p": nil, "br": nil, "strong": nil, "em": nil, "code": {"class"}, "pre": nil,
+		"ul": nil, "ol": {"start"}, "li": nil, "a": {"href", "rel", "target"},
+	}
+
+	tokenizer := html.NewTokenizer(strings.NewReader(rendered))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			attributes, ok := allowed[token.Data]
+			if !ok {
+				t.Errorf("renderMarkdown(%q) = %s, has a <%s> tag", body, rendered, token.Data)
+				continue
+			}
+			for _, attribute := range token.Attr {
+				if !slices.Contains(attributes, attribute.Key) {
+					t.Errorf("renderMarkdown(%q) = %s, has a %s attribute on <%s>", body, rendered, attribute.Key, token.Data)
+				}
+				if attribute.Key == "href" && !safeLink(attribute.Val) {
+					t.Errorf("renderMarkdown(%q) = %s, links to %q", body, rendered, attribute.Val)
+				}
+			}
+		}
+	}
+}
+
+func TestRenderMarkdownIsLinear(t *testing.T) {
+	size := 200000
+	bodies := map[string]string{
+		"openers":      strings.Repeat("*a ", size/3),
+		"underscores":  strings.Repeat("_a ", size/3),
+		"strong":       strings.Repeat("**a ", size/4),
+		"backticks":    strings.Repeat("`a", size/2),
+		"ticks":        strings.Repeat("``a`", size/4),
+		"brackets":     strings.Repeat("[", size),
+		"nested":       strings.Repeat("[a](", size/4),
+		"unsafe links": strings.Repeat("http://%/", size/9),
+		"parens":       "https://a.io/" + strings.Repeat(")", size),
+	}
+
+	for name, body := range bodies {
+		start := time.Now()
+		renderMarkdown(body)
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("rendering %s took %v", name, elapsed)
+		}
+	}
+}
+
+func TestCheckBodyLength(t *testing.T) {
+	if err := checkBodyLength(strings.Repeat("é", MaxMessageLength)); err != nil {
+		t.Errorf("body of %d characters refused: %v", MaxMessageLength, err)
+	}
+	if err := checkBodyLength(strings.Repeat("a", MaxMessageLength+1)); err == nil {
+		t.Errorf("body of %d characters accepted", MaxMessageLength+1)
+	}
+}
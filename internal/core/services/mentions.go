@@ -0,0 +1,236 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"messenger/internal/core/domain"
+)
+
+// mentionPattern matches "@email" or "@handle" when the "@" is not glued to a word,
+// so addresses written inside the text ("bob@example.com") are not taken as mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}|[A-Za-z0-9_][A-Za-z0-9_.\-]{0,31})`)
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9_][a-z0-9_.\-]{1,31}$`)
+
+// mentionToken offsets and lengths count runes, not bytes, so clients can
+// highlight the token without decoding UTF-8 themselves.
+type mentionToken struct {
+	value  string
+	text   string
+	email  bool
+	offset int
+	length int
+}
+
+func parseMentions(body string) []mentionToken {
+	var tokens []mentionToken
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(body, -1) {
+		value := strings.TrimRight(body[match[2]:match[3]], ".-")
+		if value == "" {
+			continue
+		}
+
+		text := body[match[2]-1 : match[2]+len(value)]
+		tokens = append(tokens, mentionToken{
+			value:  strings.ToLower(value),
+			text:   text,
+			email:  strings.Contains(value, "@"),
+			offset: utf8.RuneCountInString(body[:match[2]-1]),
+			length: utf8.RuneCountInString(text),
+		})
+	}
+	return tokens
+}
+
+// resolveMentions turns the tokens of a message into one mention per conversation
+// member, keeping the id and time of mentions that already existed.
+func (m *MessangerService) resolveMentions(message domain.Message, previous []*domain.Mention) ([]*domain.Mention, error) {
+	tokens := parseMentions(message.Body)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	var emails, handles []string
+	for _, token := range tokens {
+		if token.email {
+			emails = append(emails, token.value)
+		} else {
+			handles = append(handles, token.value)
+		}
+	}
+
+	users := make(map[string]*domain.User)
+	byEmail, err := m.userRepo.GetUsersByEmails(emails)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range byEmail {
+		users[strings.ToLower(user.Email)] = user
+	}
+
+	byHandle, err := m.userRepo.GetUsersByHandles(handles)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range byHandle {
+		users[user.Handle] = user
+	}
+
+	members, err := m.conversationRepo.GetMembers(message.ConversationId)
+	if err != nil {
+		return nil, err
+	}
+	isMember := make(map[string]bool, len(members))
+	for _, member := range members {
+		isMember[member.UserId] = true
+	}
+
+	existing := make(map[string]*domain.Mention)
+	for _, mention := range previous {
+		existing[mention.UserId] = mention
+	}
+
+	seen := make(map[string]bool)
+	var mentions []*domain.Mention
+	for _, token := range tokens {
+		user, ok := users[token.value]
+		if !ok || seen[user.Id] || !isMember[user.Id] {
+			continue
+		}
+		seen[user.Id] = true
+
+		mention := &domain.Mention{
+			Id:             uuid.New().String(),
+			MessageId:      message.Id,
+			ConversationId: message.ConversationId,
+			UserId:         user.Id,
+			MentionedBy:    message.UserId,
+			Token:          token.text,
+			Offset:         token.offset,
+			Length:         token.length,
+			CreatedAt:      time.Now().UTC(),
+		}
+		if old, ok := existing[user.Id]; ok {
+			mention.Id = old.Id
+			mention.CreatedAt = old.CreatedAt
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions, nil
+}
+
+func (m *MessangerService) saveMentions(message *domain.Message, previous []*domain.Mention) error {
+	mentions, err := m.resolveMentions(*message, previous)
+	if err != nil {
+		return err
+	}
+
+	message.Mentions = mentions
+	if len(mentions) == 0 && len(previous) == 0 {
+		return nil
+	}
+
+	if err := m.mentionRepo.SetMentions(message.Id, mentions); err != nil {
+		return err
+	}
+
+	notified := make(map[string]bool)
+	for _, mention := range previous {
+		notified[mention.UserId] = true
+	}
+
+	for _, mention := range mentions {
+		if notified[mention.UserId] || mention.UserId == message.UserId {
+			continue
+		}
+
+		m.publishToUser(domain.EventMentionCreated, message.ConversationId, mention.UserId, &domain.Mention{
+			Id:             mention.Id,
+			MessageId:      mention.MessageId,
+			ConversationId: mention.ConversationId,
+			UserId:         mention.UserId,
+			MentionedBy:    mention.MentionedBy,
+			Token:          mention.Token,
+			Offset:         mention.Offset,
+			Length:         mention.Length,
+			CreatedAt:      mention.CreatedAt,
+			Message:        message,
+		})
+	}
+	return nil
+}
+
+func (m *MessangerService) GetMentions(userId string, query domain.PageQuery) (*domain.MentionPage, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	page, err := m.mentionRepo.GetUserMentions(userId, query)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(page.Mentions))
+	for _, mention := range page.Mentions {
+		ids = append(ids, mention.MessageId)
+	}
+
+	byId, err := m.visibleMessages(userId, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions := make([]*domain.Mention, 0, len(page.Mentions))
+	messages := make([]*domain.Message, 0, len(page.Mentions))
+	for _, mention := range page.Mentions {
+		message, ok := byId[mention.MessageId]
+		if !ok {
+			continue
+		}
+
+		mention.Message = message
+		mentions = append(mentions, mention)
+		messages = append(messages, message)
+	}
+
+	if err := m.render(userId, messages); err != nil {
+		return nil, err
+	}
+
+	page.Mentions = mentions
+	return page, nil
+}
+
+func (m *MessangerService) attachMentions(messages []*domain.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.Id)
+	}
+
+	mentions, err := m.mentionRepo.GetMentions(ids)
+	if err != nil {
+		return err
+	}
+
+	byMessage := make(map[string][]*domain.Mention)
+	for _, mention := range mentions {
+		byMessage[mention.MessageId] = append(byMessage[mention.MessageId], mention)
+	}
+
+	for _, message := range messages {
+		message.Mentions = byMessage[message.Id]
+	}
+	return nil
+}
+
+func normalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
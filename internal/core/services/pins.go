@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"messenger/internal/core/domain"
+)
+
+const (
+	MaxPinsPerConversation = 50
+	MaxStarNoteLength      = 500
+)
+
+func (m *MessangerService) PinMessage(userId, messageId string) (*domain.Pin, error) {
+	message, err := m.getMessage(userId, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.DeletedAt != nil {
+		return nil, errors.New("cannot pin a deleted message")
+	}
+
+	pins, err := m.visiblePins(userId, message.ConversationId)
+	if err != nil {
+		return nil, err
+	}
+	if len(pins) >= MaxPinsPerConversation {
+		return nil, errors.New("conversation has too many pinned messages")
+	}
+
+	pin := domain.Pin{
+		ConversationId: message.ConversationId,
+		MessageId:      message.Id,
+		PinnedBy:       userId,
+		PinnedAt:       time.Now().UTC(),
+	}
+	if err := m.pinRepo.AddPin(pin); err != nil {
+		return nil, err
+	}
+
+	m.publish(domain.EventPinAdded, message.ConversationId, pin)
+	return &pin, nil
+}
+
+func (m *MessangerService) UnpinMessage(userId, messageId string) error {
+	message, err := m.getMessage(userId, messageId)
+	if err != nil {
+		return err
+	}
+
+	if err := m.pinRepo.RemovePin(message.ConversationId, message.Id); err != nil {
+		return err
+	}
+
+	m.publish(domain.EventPinRemoved, message.ConversationId, domain.Pin{
+		ConversationId: message.ConversationId,
+		MessageId:      message.Id,
+		PinnedBy:       userId,
+	})
+	return nil
+}
+
+func (m *MessangerService) GetPins(userId, conversationId string) ([]*domain.Pin, error) {
+	if err := checkMember(m.conversationRepo, conversationId, userId); err != nil {
+		return nil, err
+	}
+
+	pins, err := m.visiblePins(userId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*domain.Message, 0, len(pins))
+	for _, pin := range pins {
+		messages = append(messages, pin.Message)
+	}
+
+	if err := m.render(userId, messages); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+// visiblePins returns the pins of a conversation whose messages still show, so
+// pins left on deleted messages neither appear nor count towards the limit.
+func (m *MessangerService) visiblePins(userId, conversationId string) ([]*domain.Pin, error) {
+	pins, err := m.pinRepo.GetPins(conversationId)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(pins))
+	for _, pin := range pins {
+		ids = append(ids, pin.MessageId)
+	}
+
+	byId, err := m.visibleMessages(userId, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]*domain.Pin, 0, len(pins))
+	for _, pin := range pins {
+		if message, ok := byId[pin.MessageId]; ok {
+			pin.Message = message
+			visible = append(visible, pin)
+		}
+	}
+	return visible, nil
+}
+
+func (m *MessangerService) StarMessage(userId, messageId, note string) (*domain.Star, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > MaxStarNoteLength {
+		return nil, errors.New("note is too long")
+	}
+
+	message, err := m.getMessage(userId, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.DeletedAt != nil {
+		return nil, errors.New("cannot star a deleted message")
+	}
+
+	now := time.Now().UTC()
+	star, err := m.starRepo.SetStar(domain.Star{
+		Id:             uuid.New().String(),
+		UserId:         userId,
+		MessageId:      message.Id,
+		ConversationId: message.ConversationId,
+		Note:           note,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.publishToUser(domain.EventStarUpdated, message.ConversationId, userId, star)
+	return star, nil
+}
+
+func (m *MessangerService) UnstarMessage(userId, messageId string) error {
+	star := domain.Star{UserId: userId, MessageId: messageId}
+	if err := m.starRepo.RemoveStar(userId, messageId); err != nil {
+		return err
+	}
+
+	m.publishToUser(domain.EventStarRemoved, "", userId, star)
+	return nil
+}
+
+func (m *MessangerService) GetStarred(userId string, query domain.PageQuery) (*domain.StarPage, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	page, err := m.starRepo.GetStars(userId, query)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(page.Stars))
+	for _, star := range page.Stars {
+		ids = append(ids, star.MessageId)
+	}
+
+	byId, err := m.visibleMessages(userId, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	stars := make([]*domain.Star, 0, len(page.Stars))
+	messages := make([]*domain.Message, 0, len(page.Stars))
+	for _, star := range page.Stars {
+		message, ok := byId[star.MessageId]
+		if !ok {
+			continue
+		}
+
+		star.Message = message
+		stars = append(stars, star)
+		messages = append(messages, message)
+	}
+
+	if err := m.render(userId, messages); err != nil {
+		return nil, err
+	}
+
+	page.Stars = stars
+	return page, nil
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"messenger/internal/core/domain"
+)
+
+const (
+	MinPollOptions        = 2
+	MaxPollOptions        = 10
+	MaxPollQuestionLength = 300
+	MaxPollOptionLength   = 100
+)
+
+// checkType validates the payload of a new message against its type. Polls take
+// their question as the message body so they show up in search and previews.
+func checkType(message *domain.Message, hasUploads bool) error {
+	switch message.Type {
+	case "", domain.MessageText:
+		message.Type = domain.MessageText
+		message.Poll = nil
+		return nil
+	case domain.MessagePoll:
+		if hasUploads {
+			return errors.New("polls cannot have attachments")
+		}
+		if err := checkPoll(message); err != nil {
+			return err
+		}
+		message.Body = message.Poll.Question
+		return nil
+	case domain.MessageSystem:
+		return errors.New("system messages cannot be posted")
+	}
+	return errors.New("unknown message type")
+}
+
+func checkPoll(message *domain.Message) error {
+	poll := message.Poll
+	if poll == nil {
+		return errors.New("poll is required")
+	}
+
+	poll.Question = strings.TrimSpace(poll.Question)
+	if poll.Question == "" {
+		poll.Question = strings.TrimSpace(message.Body)
+	}
+	if poll.Question == "" {
+		return errors.New("poll question is required")
+	}
+	if len([]rune(poll.Question)) > MaxPollQuestionLength {
+		return errors.New("poll question is too long")
+	}
+
+	if len(poll.Options) < MinPollOptions || len(poll.Options) > MaxPollOptions {
+		return errors.New("poll needs between 2 and 10 options")
+	}
+
+	seen := make(map[string]bool)
+	options := make([]domain.PollOption, 0, len(poll.Options))
+	for i, option := range poll.Options {
+		text := strings.TrimSpace(option.Text)
+		if text == "" {
+			return errors.New("poll options cannot be empty")
+		}
+		if len([]rune(text)) > MaxPollOptionLength {
+			return errors.New("poll option is too long")
+		}
+		if seen[strings.ToLower(text)] {
+			return errors.New("poll options must be unique")
+		}
+		seen[strings.ToLower(text)] = true
+
+		options = append(options, domain.PollOption{Id: strconv.Itoa(i + 1), Text: text})
+	}
+	poll.Options = options
+
+	if poll.ClosesAt != nil {
+		closesAt := poll.ClosesAt.UTC()
+		if !closesAt.After(time.Now().UTC()) {
+			return errors.New("closes_at must be in the future")
+		}
+		if closesAt.After(time.Now().UTC().Add(MaxMessageLifetime)) {
+			return errors.New("closes_at is too far in the future")
+		}
+		poll.ClosesAt = &closesAt
+	}
+	poll.ClosedAt = nil
+	return nil
+}
+
+func (m *MessangerService) Vote(userId, messageId string, optionIds []string) (*domain.Poll, error) {
+	poll, err := m.openPoll(userId, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := make(map[string]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		valid[option.Id] = true
+	}
+
+	seen := make(map[string]bool)
+	var choices []string
+	for _, optionId := range optionIds {
+		if !valid[optionId] {
+			return nil, errors.New("unknown poll option")
+		}
+		if !seen[optionId] {
+			seen[optionId] = true
+			choices = append(choices, optionId)
+		}
+	}
+
+	if len(choices) == 0 {
+		return nil, errors.New("option_ids is required")
+	}
+	if len(choices) > 1 && !poll.MultipleChoice {
+		return nil, errors.New("this poll allows a single choice")
+	}
+
+	now := time.Now().UTC()
+	err = m.pollRepo.CastVote(domain.PollVote{
+		MessageId: messageId,
+		UserId:    userId,
+		OptionIds: choices,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.publishPoll(userId, poll)
+}
+
+func (m *MessangerService) RetractVote(userId, messageId string) (*domain.Poll, error) {
+	poll, err := m.openPoll(userId, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.pollRepo.RemoveVote(messageId, userId, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return m.publishPoll(userId, poll)
+}
+
+func (m *MessangerService) ClosePoll(userId, messageId string) (*domain.Poll, error) {
+	message, poll, err := m.getPoll(userId, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.UserId != userId {
+		return nil, errors.New("only the author can close a poll")
+	}
+	if poll.Closed {
+		return nil, errors.New("poll is already closed")
+	}
+
+	closedAt := time.Now().UTC()
+	if err := m.pollRepo.ClosePoll(messageId, closedAt); err != nil {
+		return nil, err
+	}
+	poll.ClosedAt = &closedAt
+	return m.publishPoll(userId, poll)
+}
+
+func (m *MessangerService) openPoll(userId, messageId string) (*domain.Poll, error) {
+	_, poll, err := m.getPoll(userId, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if poll.Closed {
+		return nil, domain.ErrPollClosed
+	}
+	return poll, nil
+}
+
+func (m *MessangerService) getPoll(userId, messageId string) (*domain.Message, *domain.Poll, error) {
+	message, err := m.getMessage(userId, messageId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if message.DeletedAt != nil {
+		return nil, nil, errors.New("message is deleted")
+	}
+	if message.Type != domain.MessagePoll {
+		return nil, nil, errors.New("message is not a poll")
+	}
+
+	if err := m.attachPolls(userId, []*domain.Message{message}); err != nil {
+		return nil, nil, err
+	}
+	if message.Poll == nil {
+		return nil, nil, errors.New("poll not found")
+	}
+	return message, message.Poll, nil
+}
+
+// publishPoll reloads the tallies, pushes them to the conversation and returns
+// the poll as the caller sees it.
+func (m *MessangerService) publishPoll(userId string, poll *domain.Poll) (*domain.Poll, error) {
+	message := &domain.Message{Id: poll.MessageId, Type: domain.MessagePoll}
+	if err := m.attachPolls(userId, []*domain.Message{message}); err != nil {
+		return nil, err
+	}
+	if message.Poll == nil {
+		return nil, errors.New("poll not found")
+	}
+
+	broadcast := *message.Poll
+	broadcast.MyVote = nil
+	m.publish(domain.EventPollUpdated, poll.ConversationId, &broadcast)
+	return message.Poll, nil
+}
+
+func (m *MessangerService) attachPolls(userId string, messages []*domain.Message) error {
+	var ids []string
+	for _, message := range messages {
+		if message.Type == domain.MessagePoll {
+			ids = append(ids, message.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	polls, err := m.pollRepo.GetPolls(ids)
+	if err != nil {
+		return err
+	}
+
+	votes, err := m.pollRepo.GetVotes(ids)
+	if err != nil {
+		return err
+	}
+
+	byMessage := make(map[string][]*domain.PollVote)
+	for _, vote := range votes {
+		byMessage[vote.MessageId] = append(byMessage[vote.MessageId], vote)
+	}
+
+	now := time.Now().UTC()
+	byId := make(map[string]*domain.Poll, len(polls))
+	for _, poll := range polls {
+		tallyPoll(poll, byMessage[poll.MessageId], userId, now)
+		byId[poll.MessageId] = poll
+	}
+
+	for _, message := range messages {
+		if message.Type == domain.MessagePoll {
+			message.Poll = byId[message.Id]
+		}
+	}
+	return nil
+}
+
+// tallyPoll counts the ballots per option. Voter ids are only listed when the
+// poll is not anonymous; the caller always sees their own choice.
+func tallyPoll(poll *domain.Poll, votes []*domain.PollVote, userId string, now time.Time) {
+	index := make(map[string]int, len(poll.Options))
+	for i := range poll.Options {
+		poll.Options[i].Votes = 0
+		poll.Options[i].Voters = nil
+		index[poll.Options[i].Id] = i
+	}
+
+	poll.TotalVotes = 0
+	poll.MyVote = nil
+	for _, vote := range votes {
+		counted := false
+		for _, optionId := range vote.OptionIds {
+			i, ok := index[optionId]
+			if !ok {
+				continue
+			}
+			counted = true
+			poll.Options[i].Votes++
+			if !poll.Anonymous {
+				poll.Options[i].Voters = append(poll.Options[i].Voters, vote.UserId)
+			}
+		}
+		if counted {
+			poll.TotalVotes++
+		}
+		if userId != "" && vote.UserId == userId {
+			poll.MyVote = vote.OptionIds
+		}
+	}
+
+	poll.Closed = poll.ClosedAt != nil || (poll.ClosesAt != nil && !poll.ClosesAt.After(now))
+}
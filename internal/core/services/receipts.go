@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"messenger/internal/core/domain"
+)
+
+// receiptState holds, for one conversation, how far each member has received and read.
+type receiptState struct {
+	members   []*domain.ConversationMember
+	delivered map[string]time.Time
+	read      map[string]time.Time
+}
+
+func (m *MessangerService) MarkDelivered(userId, conversationId, messageId string) error {
+	if err := checkMember(m.conversationRepo, conversationId, userId); err != nil {
+		return err
+	}
+
+	message, err := m.repo.GetOneMessage(messageId)
+	if err != nil {
+		return err
+	}
+
+	if message.ConversationId != conversationId {
+		return errors.New("message does not belong to this conversation")
+	}
+	return m.markDelivered(userId, message)
+}
+
+func (m *MessangerService) markDelivered(userId string, message *domain.Message) error {
+	marker := domain.DeliveryMarker{
+		ConversationId:         message.ConversationId,
+		UserId:                 userId,
+		LastDeliveredMessageId: message.Id,
+		LastDeliveredAt:        message.CreatedAt,
+		UpdatedAt:              time.Now().UTC(),
+	}
+
+	advanced, err := m.receiptRepo.SetDeliveryMarker(marker)
+	if err != nil {
+		return err
+	}
+
+	if advanced {
+		m.publish(domain.EventDeliveryUpdated, message.ConversationId, marker)
+	}
+	return nil
+}
+
+// acknowledge counts messages fetched by a member as delivered to them.
+func (m *MessangerService) acknowledge(userId string, messages []*domain.Message) {
+	var newest *domain.Message
+	for _, message := range messages {
+		if message.UserId != userId && (newest == nil || message.CreatedAt.After(newest.CreatedAt)) {
+			newest = message
+		}
+	}
+
+	if newest == nil {
+		return
+	}
+	if err := m.markDelivered(userId, newest); err != nil {
+		log.Printf("acknowledge %s for %s: %v", newest.Id, userId, err)
+	}
+}
+
+func (m *MessangerService) GetReceipts(userId, messageId string) ([]*domain.Receipt, error) {
+	message, err := m.getMessage(userId, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.UserId != userId {
+		return nil, errors.New("only the author can see the receipts of a message")
+	}
+	if message.DeletedAt != nil {
+		return nil, errors.New("message is deleted")
+	}
+
+	state, err := m.loadReceiptState(message.ConversationId)
+	if err != nil {
+		return nil, err
+	}
+	return state.receipts(message), nil
+}
+
+// attachDelivery sets the combined delivery status on the messages the caller authored.
+func (m *MessangerService) attachDelivery(userId string, messages []*domain.Message) error {
+	states := make(map[string]*receiptState)
+	for _, message := range messages {
+		if message.UserId != userId || message.DeletedAt != nil {
+			continue
+		}
+
+		state, ok := states[message.ConversationId]
+		if !ok {
+			var err error
+			if state, err = m.loadReceiptState(message.ConversationId); err != nil {
+				return err
+			}
+			states[message.ConversationId] = state
+		}
+
+		message.Delivery = summarizeReceipts(state.receipts(message))
+	}
+	return nil
+}
+
+func (m *MessangerService) loadReceiptState(conversationId string) (*receiptState, error) {
+	members, err := m.conversationRepo.GetMembers(conversationId)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveryMarkers, err := m.receiptRepo.GetDeliveryMarkers(conversationId)
+	if err != nil {
+		return nil, err
+	}
+
+	readMarkers, err := m.readRepo.GetReadMarkers(conversationId)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &receiptState{
+		members:   members,
+		delivered: make(map[string]time.Time, len(deliveryMarkers)),
+		read:      make(map[string]time.Time, len(readMarkers)),
+	}
+	for _, marker := range deliveryMarkers {
+		state.delivered[marker.UserId] = marker.LastDeliveredAt
+	}
+	for _, marker := range readMarkers {
+		state.read[marker.UserId] = marker.LastReadAt
+	}
+	return state, nil
+}
+
+// receipts lists the status of a message for every member who was in the
+// conversation when it was sent, other than its author.
+func (s *receiptState) receipts(message *domain.Message) []*domain.Receipt {
+	receipts := make([]*domain.Receipt, 0, len(s.members))
+	for _, member := range s.members {
+		if member.UserId == message.UserId || member.JoinedAt.After(message.CreatedAt) {
+			continue
+		}
+
+		status := domain.DeliverySent
+		if read, ok := s.read[member.UserId]; ok && !read.Before(message.CreatedAt) {
+			status = domain.DeliveryRead
+		} else if delivered, ok := s.delivered[member.UserId]; ok && !delivered.Before(message.CreatedAt) {
+			status = domain.DeliveryDelivered
+		}
+
+		receipts = append(receipts, &domain.Receipt{
+			MessageId: message.Id,
+			UserId:    member.UserId,
+			Status:    status,
+		})
+	}
+	return receipts
+}
+
+func summarizeReceipts(receipts []*domain.Receipt) *domain.DeliveryStatus {
+	summary := &domain.DeliveryStatus{Status: domain.DeliverySent, Recipients: len(receipts)}
+	for _, receipt := range receipts {
+		switch receipt.Status {
+		case domain.DeliveryRead:
+			summary.Read++
+			summary.Delivered++
+		case domain.DeliveryDelivered:
+			summary.Delivered++
+		}
+	}
+
+	if summary.Recipients > 0 && summary.Read == summary.Recipients {
+		summary.Status = domain.DeliveryRead
+	} else if summary.Recipients > 0 && summary.Delivered == summary.Recipients {
+		summary.Status = domain.DeliveryDelivered
+	}
+	return summary
+}
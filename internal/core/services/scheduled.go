@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"messenger/internal/core/domain"
+)
+
+const (
+	MaxScheduleAhead  = 365 * 24 * time.Hour
+	scheduleLease     = time.Minute
+	scheduleBatchSize = 100
+)
+
+func (m *MessangerService) ScheduleMessage(userId string, message domain.Message) (*domain.ScheduledMessage, error) {
+	if message.SendAt == nil {
+		return nil, errors.New("send_at is required")
+	}
+
+	sendAt, err := checkSendAt(*message.SendAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.ExpiresAt != nil || message.ExpireAfterRead != 0 {
+		return nil, errors.New("ephemeral messages cannot be scheduled")
+	}
+
+	if message.Type != "" && message.Type != domain.MessageText {
+		return nil, errors.New("only text messages can be scheduled")
+	}
+
+	if err := m.checkNewMessage(userId, &message, false); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	scheduled := domain.ScheduledMessage{
+		Id:             uuid.New().String(),
+		ConversationId: message.ConversationId,
+		UserId:         userId,
+		Body:           message.Body,
+		ParentId:       message.ParentId,
+		QuoteId:        message.QuoteId,
+		SendAt:         sendAt,
+		Status:         domain.ScheduledPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := m.scheduledRepo.AddScheduledMessage(scheduled); err != nil {
+		return nil, err
+	}
+
+	m.clearDraft(userId, scheduled.ConversationId, scheduled.ParentId)
+	return &scheduled, nil
+}
+
+func (m *MessangerService) GetScheduledMessages(userId, conversationId string) ([]*domain.ScheduledMessage, error) {
+	if conversationId != "" {
+		if err := checkMember(m.conversationRepo, conversationId, userId); err != nil {
+			return nil, err
+		}
+	}
+	return m.scheduledRepo.GetScheduledMessages(userId, conversationId)
+}
+
+func (m *MessangerService) UpdateScheduledMessage(userId, id, body string, sendAt *time.Time) (*domain.ScheduledMessage, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, errors.New("message needs a body")
+	}
+
+	if err := checkBodyLength(body); err != nil {
+		return nil, err
+	}
+
+	if sendAt == nil {
+		return nil, errors.New("send_at is required")
+	}
+
+	at, err := checkSendAt(*sendAt)
+	if err != nil {
+		return nil, err
+	}
+	return m.scheduledRepo.UpdateScheduledMessage(id, userId, body, at, time.Now().UTC())
+}
+
+func (m *MessangerService) CancelScheduledMessage(userId, id string) error {
+	return m.scheduledRepo.CancelScheduledMessage(id, userId, time.Now().UTC())
+}
+
+// StartScheduler polls the persisted queue, so messages scheduled before a restart
+// still go out. Claims are leased per instance, so running several is safe.
+func (m *MessangerService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := m.SendDueMessages(); err != nil {
+				log.Printf("send scheduled messages: %v", err)
+			} else if count > 0 {
+				log.Printf("sent %d scheduled messages", count)
+			}
+		}
+	}()
+}
+
+func (m *MessangerService) SendDueMessages() (int, error) {
+	claimed, err := m.scheduledRepo.ClaimDueMessages(time.Now().UTC(), m.instanceId, scheduleLease, scheduleBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, scheduled := range claimed {
+		status, reason := domain.ScheduledSent, ""
+
+		// the message reuses the schedule id, so a retry after a lost lease
+		// finds the message already posted instead of posting it twice
+		if _, err := m.repo.GetOneMessage(scheduled.Id); err != nil {
+			_, err := m.createMessage(scheduled.UserId, scheduled.Id, domain.Message{
+				ConversationId: scheduled.ConversationId,
+				Body:           scheduled.Body,
+				ParentId:       scheduled.ParentId,
+				QuoteId:        scheduled.QuoteId,
+			}, nil)
+			if err != nil {
+				status, reason = domain.ScheduledFailed, err.Error()
+			}
+		}
+
+		if err := m.scheduledRepo.FinishScheduledMessage(scheduled.Id, m.instanceId, status, reason, time.Now().UTC()); err != nil {
+			log.Printf("finish scheduled message %s: %v", scheduled.Id, err)
+			continue
+		}
+		if status == domain.ScheduledSent {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func checkSendAt(sendAt time.Time) (time.Time, error) {
+	sendAt = sendAt.UTC()
+	now := time.Now().UTC()
+
+	if !sendAt.After(now) {
+		return sendAt, errors.New("send_at must be in the future")
+	}
+	if sendAt.After(now.Add(MaxScheduleAhead)) {
+		return sendAt, errors.New("send_at is too far in the future")
+	}
+	return sendAt, nil
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"messenger/internal/core/domain"
+	"messenger/internal/core/ports"
+)
+
+var ErrNotConversationMember = errors.New("user is not a member of this conversation")
+
+type ConversationService struct {
+	repo     ports.ConversationRepository
+	userRepo ports.UserRepository
+}
+
+func NewConversationService(repo ports.ConversationRepository, userRepo ports.UserRepository) *ConversationService {
+	return &ConversationService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func (c *ConversationService) CreateDirectConversation(userId, otherUserId string) (*domain.Conversation, error) {
+	if otherUserId == "" || otherUserId == userId {
+		return nil, errors.New("direct conversation needs another user")
+	}
+
+	if _, err := c.userRepo.GetOneUser(otherUserId); err != nil {
+		return nil, err
+	}
+
+	directKey := directConversationKey(userId, otherUserId)
+	existing, err := c.repo.GetDirectConversation(directKey)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, domain.ErrConversationNotFound) {
+		return nil, err
+	}
+
+	conversation := domain.Conversation{
+		Id:        uuid.New().String(),
+		Type:      domain.ConversationDirect,
+		DirectKey: directKey,
+		CreatedBy: userId,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	// a concurrent request may have created it first, in which case that one is returned
+	err = c.repo.CreateConversation(conversation, []string{userId, otherUserId})
+	if errors.Is(err, domain.ErrDirectConversationExists) {
+		return c.repo.GetDirectConversation(directKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+func (c *ConversationService) CreateGroupConversation(userId, name string, memberIds []string) (*domain.Conversation, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("group conversation needs a name")
+	}
+
+	members := []string{userId}
+	seen := map[string]bool{userId: true}
+	for _, memberId := range memberIds {
+		if seen[memberId] {
+			continue
+		}
+		if _, err := c.userRepo.GetOneUser(memberId); err != nil {
+			return nil, err
+		}
+		seen[memberId] = true
+		members = append(members, memberId)
+	}
+
+	conversation := domain.Conversation{
+		Id:        uuid.New().String(),
+		Type:      domain.ConversationGroup,
+		Name:      name,
+		CreatedBy: userId,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	if err := c.repo.CreateConversation(conversation, members); err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+func (c *ConversationService) GetOneConversation(userId, id string) (*domain.Conversation, error) {
+	if err := c.CheckMember(id, userId); err != nil {
+		return nil, err
+	}
+	return c.repo.GetOneConversation(id)
+}
+
+func (c *ConversationService) GetUserConversations(userId string) ([]*domain.Conversation, error) {
+	return c.repo.GetUserConversations(userId)
+}
+
+func (c *ConversationService) GetMembers(userId, conversationId string) ([]*domain.ConversationMember, error) {
+	if err := c.CheckMember(conversationId, userId); err != nil {
+		return nil, err
+	}
+	return c.repo.GetMembers(conversationId)
+}
+
+func (c *ConversationService) AddMember(userId, conversationId, memberId string) error {
+	conversation, err := c.GetOneConversation(userId, conversationId)
+	if err != nil {
+		return err
+	}
+
+	if conversation.Type != domain.ConversationGroup {
+		return errors.New("members can only be added to group conversations")
+	}
+
+	if _, err := c.userRepo.GetOneUser(memberId); err != nil {
+		return err
+	}
+
+	return c.repo.AddMember(domain.ConversationMember{
+		ConversationId: conversationId,
+		UserId:         memberId,
+		JoinedAt:       time.Now().UTC(),
+	})
+}
+
+func (c *ConversationService) RemoveMember(userId, conversationId, memberId string) error {
+	conversation, err := c.GetOneConversation(userId, conversationId)
+	if err != nil {
+		return err
+	}
+
+	if conversation.Type != domain.ConversationGroup {
+		return errors.New("members can only be removed from group conversations")
+	}
+
+	if memberId != userId && conversation.CreatedBy != userId {
+		return errors.New("only the group creator can remove other members")
+	}
+
+	return c.repo.RemoveMember(conversationId, memberId)
+}
+
+func (c *ConversationService) CheckMember(conversationId, userId string) error {
+	return checkMember(c.repo, conversationId, userId)
+}
+
+// checkMember is shared by the services that guard a conversation's data.
+func checkMember(conversationRepo ports.ConversationRepository, conversationId, userId string) error {
+	ok, err := conversationRepo.IsMember(conversationId, userId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotConversationMember
+	}
+	return nil
+}
+
+func directConversationKey(userId, otherUserId string) string {
+	ids := []string{userId, otherUserId}
+	sort.Strings(ids)
+	return strings.Join(ids, ":")
+}
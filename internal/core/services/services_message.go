@@ -1,39 +1,1167 @@
 package services
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
 	"github.com/google/uuid"
 	"messenger/internal/core/domain"
 	"messenger/internal/core/ports"
 )
 
+var allowedMimeTypes = map[string][]string{
+	"image/jpeg":      {".jpg", ".jpeg"},
+	"image/png":       {".png"},
+	"image/gif":       {".gif"},
+	"image/webp":      {".webp"},
+	"application/pdf": {".pdf"},
+	"application/zip": {".zip", ".docx", ".xlsx", ".pptx"},
+	"text/plain":      {".txt", ".log", ".csv", ".md", ".json"},
+	"audio/mpeg":      {".mp3"},
+	"video/mp4":       {".mp4"},
+}
+
+const (
+	MaxMessageLifetime = 365 * 24 * time.Hour
+	MaxMessageLength   = 10000
+)
+
+const (
+	DefaultRestoreWindow = 24 * time.Hour
+	DefaultRetention     = 30 * 24 * time.Hour
+)
+
 type MessangerService struct {
-	repo ports.MessangerRepository
+	repo             ports.MessangerRepository
+	conversationRepo ports.ConversationRepository
+	reactionRepo     ports.ReactionRepository
+	revisionRepo     ports.RevisionRepository
+	attachmentRepo   ports.AttachmentRepository
+	mentionRepo      ports.MentionRepository
+	pinRepo          ports.PinRepository
+	starRepo         ports.StarRepository
+	scheduledRepo    ports.ScheduledMessageRepository
+	pollRepo         ports.PollRepository
+	linkPreviewRepo  ports.LinkPreviewRepository
+	draftRepo        ports.DraftRepository
+	receiptRepo      ports.ReceiptRepository
+	readRepo         ports.ReadMarkerRepository
+	incomingRepo     ports.IncomingWebhookRepository
+	userRepo         ports.UserRepository
+	blobs            ports.BlobStorage
+	previews         *PreviewService
+	unfurls          *UnfurlService
+	webhooks         *WebhookService
+	incomingLimiter  *rateLimiter
+	events           ports.EventPublisher
+	instanceId       string
+	restoreWindow    time.Duration
+	retention        time.Duration
 }
 
-func NewMessangerService(repo ports.MessangerRepository) *MessangerService {
+func NewMessangerService(repo ports.MessangerRepository, conversationRepo ports.ConversationRepository, reactionRepo ports.ReactionRepository, revisionRepo ports.RevisionRepository, attachmentRepo ports.AttachmentRepository, mentionRepo ports.MentionRepository, pinRepo ports.PinRepository, starRepo ports.StarRepository, scheduledRepo ports.ScheduledMessageRepository, pollRepo ports.PollRepository, linkPreviewRepo ports.LinkPreviewRepository, draftRepo ports.DraftRepository, receiptRepo ports.ReceiptRepository, readRepo ports.ReadMarkerRepository, incomingRepo ports.IncomingWebhookRepository, userRepo ports.UserRepository, blobs ports.BlobStorage, events ports.EventPublisher) *MessangerService {
 	return &MessangerService{
-		repo: repo,
+		repo:             repo,
+		conversationRepo: conversationRepo,
+		reactionRepo:     reactionRepo,
+		revisionRepo:     revisionRepo,
+		attachmentRepo:   attachmentRepo,
+		mentionRepo:      mentionRepo,
+		pinRepo:          pinRepo,
+		starRepo:         starRepo,
+		scheduledRepo:    scheduledRepo,
+		pollRepo:         pollRepo,
+		linkPreviewRepo:  linkPreviewRepo,
+		draftRepo:        draftRepo,
+		receiptRepo:      receiptRepo,
+		readRepo:         readRepo,
+		incomingRepo:     incomingRepo,
+		instanceId:       uuid.New().String(),
+		userRepo:         userRepo,
+		blobs:            blobs,
+		events:           events,
+		restoreWindow:    DefaultRestoreWindow,
+		retention:        DefaultRetention,
+	}
+}
+
+func (m *MessangerService) SetDeletePolicy(restoreWindow, retention time.Duration) {
+	if retention < restoreWindow {
+		retention = restoreWindow
 	}
+	m.restoreWindow = restoreWindow
+	m.retention = retention
 }
 
 func (m *MessangerService) CreateMessage(userId string, message domain.Message) error {
-	message.Id = uuid.New().String()
+	_, err := m.CreateMessageWithAttachments(userId, message, nil)
+	return err
+}
+
+func (m *MessangerService) CreateMessageWithAttachments(userId string, message domain.Message, uploads []domain.Upload) (*domain.Message, error) {
+	message.ForwardedFromId = ""
+	message.ForwardedFromUserId = ""
+	message.ForwardedFromConversationId = ""
+	message.BotName = ""
+
+	created, err := m.createMessage(userId, uuid.New().String(), message, uploads)
+	if err != nil {
+		return nil, err
+	}
+
+	m.clearDraft(userId, created.ConversationId, created.ParentId)
+
+	if err := m.attachDelivery(userId, []*domain.Message{created}); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (m *MessangerService) ForwardMessage(userId, id, conversationId string) (*domain.Message, error) {
+	source, err := m.getMessage(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if source.DeletedAt != nil {
+		return nil, errors.New("cannot forward a deleted message")
+	}
+
+	if source.ExpiresAt != nil || source.ExpireAfterRead > 0 {
+		return nil, errors.New("ephemeral messages cannot be forwarded")
+	}
+
+	if source.Type == domain.MessagePoll {
+		return nil, errors.New("polls cannot be forwarded")
+	}
+
+	attachments, err := m.attachmentRepo.GetAttachments([]string{source.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make([]domain.Upload, 0, len(attachments))
+	for _, attachment := range attachments {
+		content, err := m.blobs.Get(attachment.StorageKey)
+		if err != nil {
+			return nil, err
+		}
+		defer content.Close()
+
+		uploads = append(uploads, domain.Upload{
+			FileName: attachment.FileName,
+			Size:     attachment.Size,
+			Content:  content,
+		})
+	}
+
+	// a forwarded forward keeps pointing at the original message
+	message := domain.Message{
+		ConversationId:              conversationId,
+		Body:                        source.Body,
+		ForwardedFromId:             source.Id,
+		ForwardedFromUserId:         source.UserId,
+		ForwardedFromConversationId: source.ConversationId,
+	}
+	if source.ForwardedFromId != "" {
+		message.ForwardedFromId = source.ForwardedFromId
+		message.ForwardedFromUserId = source.ForwardedFromUserId
+		message.ForwardedFromConversationId = source.ForwardedFromConversationId
+	}
+
+	return m.createMessage(userId, uuid.New().String(), message, uploads)
+}
+
+func (m *MessangerService) createMessage(userId, id string, message domain.Message, uploads []domain.Upload) (*domain.Message, error) {
+	if err := m.checkNewMessage(userId, &message, len(uploads) > 0); err != nil {
+		return nil, err
+	}
+
+	if err := checkExpiry(&message); err != nil {
+		return nil, err
+	}
+
+	message.Id = id
+	message.SendAt = nil
 	message.UserId = userId
-	return m.repo.CreateMessage(message)
+	message.Edited = false
+	message.EditedAt = nil
+	message.ReplyCount = 0
+	message.LastReplyAt = nil
+	message.DeletedAt = nil
+	message.DeletedBy = ""
+	message.CreatedAt = time.Now().UTC()
+	message.UpdatedAt = message.CreatedAt
+
+	// computed fields are filled in by the server, never taken from the client
+	message.BodyHtml = ""
+	message.Reactions = nil
+	message.Attachments = nil
+	message.Mentions = nil
+	message.Quote = nil
+	message.Previews = nil
+	message.Delivery = nil
+
+	if message.Poll != nil {
+		message.Poll.MessageId = message.Id
+		message.Poll.ConversationId = message.ConversationId
+		message.Poll.CreatedAt = message.CreatedAt
+		if err := m.pollRepo.CreatePoll(*message.Poll); err != nil {
+			return nil, err
+		}
+		tallyPoll(message.Poll, nil, userId, message.CreatedAt)
+	}
+
+	attachments, err := m.storeUploads(userId, message, uploads)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.repo.CreateMessage(message); err != nil {
+		m.discardAttachments(message.Id, attachments)
+		if message.Poll != nil {
+			_ = m.pollRepo.DeletePolls([]string{message.Id})
+		}
+		return nil, err
+	}
+	message.Attachments = attachments
+
+	// a forwarded copy repeats someone else's words, so its mentions notify
+	// nobody; the message is already stored, so a failure here is only logged
+	if message.ForwardedFromId == "" {
+		if err := m.saveMentions(&message, nil); err != nil {
+			log.Printf("save mentions of message %s: %v", message.Id, err)
+		}
+	}
+
+	if err := m.attachQuotes([]*domain.Message{&message}); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range attachments {
+		if attachment.PreviewStatus == domain.PreviewPending {
+			m.previews.Enqueue(attachment)
+		}
+	}
+
+	m.enqueueUnfurl(&message, false)
+
+	message.BodyHtml = renderMarkdown(message.Body)
+	m.publish(domain.EventMessageCreated, message.ConversationId, message)
+	return &message, nil
+}
+
+func checkBodyLength(body string) error {
+	if utf8.RuneCountInString(body) > MaxMessageLength {
+		return errors.New(fmt.Sprintf("message body is longer than %d characters", MaxMessageLength))
+	}
+	return nil
+}
+
+// checkNewMessage validates a message about to be posted and resolves its
+// conversation and thread root from the parent.
+func (m *MessangerService) checkNewMessage(userId string, message *domain.Message, hasUploads bool) error {
+	if err := checkType(message, hasUploads); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(message.Body) == "" && !hasUploads {
+		return errors.New("message needs a body or attachments")
+	}
+
+	if err := checkBodyLength(message.Body); err != nil {
+		return err
+	}
+
+	message.ThreadRootId = ""
+	if message.ParentId != "" {
+		parent, err := m.repo.GetOneMessage(message.ParentId)
+		if err != nil {
+			return err
+		}
+		if parent.DeletedAt != nil {
+			return errors.New("cannot reply to a deleted message")
+		}
+		if message.ConversationId == "" {
+			message.ConversationId = parent.ConversationId
+		}
+		if message.ConversationId != parent.ConversationId {
+			return errors.New("reply must be in the same conversation as its parent")
+		}
+
+		message.ThreadRootId = parent.Id
+		if parent.ThreadRootId != "" {
+			message.ThreadRootId = parent.ThreadRootId
+		}
+	}
+
+	if message.ConversationId == "" {
+		return errors.New("conversation_id is required")
+	}
+
+	if err := m.checkAuthor(message.ConversationId, userId); err != nil {
+		return err
+	}
+
+	if message.QuoteId != "" {
+		quote, err := m.repo.GetOneMessage(message.QuoteId)
+		if err != nil {
+			return err
+		}
+		// quotes are rendered live for every member, so they must not reach outside the conversation
+		if quote.ConversationId != message.ConversationId {
+			return errors.New("only messages of the same conversation can be quoted")
+		}
+		if quote.DeletedAt != nil {
+			return errors.New("cannot quote a deleted message")
+		}
+	}
+	return nil
+}
+
+func checkExpiry(message *domain.Message) error {
+	if message.ExpireAfterRead < 0 || time.Duration(message.ExpireAfterRead)*time.Second > MaxMessageLifetime {
+		return errors.New("expire_after_read is out of range")
+	}
+
+	if message.ExpiresAt != nil {
+		expiresAt := message.ExpiresAt.UTC()
+		if !expiresAt.After(time.Now().UTC()) {
+			return errors.New("expires_at must be in the future")
+		}
+		if expiresAt.After(time.Now().UTC().Add(MaxMessageLifetime)) {
+			return errors.New("expires_at is too far in the future")
+		}
+		message.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+func (m *MessangerService) GetAttachment(userId, id string) (*domain.Attachment, io.ReadCloser, error) {
+	attachment, err := m.attachmentRepo.GetAttachment(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	message, err := m.getMessage(userId, attachment.MessageId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if message.DeletedAt != nil {
+		return nil, nil, errors.New("message is deleted")
+	}
+
+	content, err := m.blobs.Get(attachment.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+func (m *MessangerService) GetThumbnail(userId, attachmentId, size string) (*domain.Thumbnail, io.ReadCloser, error) {
+	attachment, err := m.attachmentRepo.GetAttachment(attachmentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	message, err := m.getMessage(userId, attachment.MessageId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if message.DeletedAt != nil {
+		return nil, nil, errors.New("message is deleted")
+	}
+
+	thumbnails, err := m.attachmentRepo.GetThumbnails([]string{attachment.Id})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, thumbnail := range thumbnails {
+		if thumbnail.Size == size {
+			content, err := m.blobs.Get(thumbnail.StorageKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			return thumbnail, content, nil
+		}
+	}
+	return nil, nil, errors.New("thumbnail not found")
+}
+
+func (m *MessangerService) GetOneMessage(userId, id string) (*domain.Message, error) {
+	message, err := m.getMessage(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.render(userId, []*domain.Message{message}); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (m *MessangerService) GetAllMessages(userId string, filter domain.MessageFilter) (*domain.MessagePage, error) {
+	if filter.ConversationId == "" {
+		return nil, errors.New("conversation_id is required")
+	}
+
+	if err := filter.Normalize(); err != nil {
+		return nil, err
+	}
+
+	if err := checkMember(m.conversationRepo, filter.ConversationId, userId); err != nil {
+		return nil, err
+	}
+
+	page, err := m.repo.GetAllMessages(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	m.acknowledge(userId, page.Messages)
+
+	if err := m.render(userId, page.Messages); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (m *MessangerService) GetReplies(userId, id string, query domain.PageQuery) (*domain.MessagePage, error) {
+	message, err := m.getMessage(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	rootId := message.Id
+	if message.ThreadRootId != "" {
+		rootId = message.ThreadRootId
+	}
+
+	page, err := m.repo.GetAllMessages(domain.MessageFilter{
+		PageQuery:      query,
+		ConversationId: message.ConversationId,
+		ThreadRootId:   rootId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// replies are not acknowledged: the delivery marker follows the main timeline,
+	// and moving it to a reply would mark older unseen messages as delivered
+
+	if err := m.render(userId, page.Messages); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (m *MessangerService) AddReaction(userId, messageId, emoji string) error {
+	emoji, err := validateEmoji(emoji)
+	if err != nil {
+		return err
+	}
+
+	message, err := m.getMessage(userId, messageId)
+	if err != nil {
+		return err
+	}
+
+	if message.DeletedAt != nil {
+		return errors.New("cannot react to a deleted message")
+	}
+
+	reaction := domain.Reaction{
+		MessageId: message.Id,
+		UserId:    userId,
+		Emoji:     emoji,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := m.reactionRepo.AddReaction(reaction); err != nil {
+		return err
+	}
+
+	m.publish(domain.EventReactionAdded, message.ConversationId, reaction)
+	return nil
 }
 
-func (m *MessangerService) GetOneMessage(id string) (*domain.Message, error) {
-	return m.repo.GetOneMessage(id)
+func (m *MessangerService) RemoveReaction(userId, messageId, emoji string) error {
+	emoji, err := validateEmoji(emoji)
+	if err != nil {
+		return err
+	}
+
+	message, err := m.getMessage(userId, messageId)
+	if err != nil {
+		return err
+	}
+
+	if err := m.reactionRepo.RemoveReaction(message.Id, userId, emoji); err != nil {
+		return err
+	}
+
+	m.publish(domain.EventReactionRemoved, message.ConversationId, domain.Reaction{
+		MessageId: message.Id,
+		UserId:    userId,
+		Emoji:     emoji,
+	})
+	return nil
 }
 
-func (m *MessangerService) GetAllMessages() ([]*domain.Message, error) {
-	return m.repo.GetAllMessages()
+func (m *MessangerService) SearchMessages(userId, conversationId, q, cursor string, limit int) (*domain.SearchPage, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, errors.New("q is required")
+	}
+
+	offset, err := domain.DecodeOffsetCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	pageQuery := domain.PageQuery{Limit: limit}
+	if err := pageQuery.Normalize(); err != nil {
+		return nil, err
+	}
+
+	query := domain.SearchQuery{
+		Query:  q,
+		Limit:  pageQuery.Limit,
+		Offset: offset,
+	}
+
+	if conversationId != "" {
+		if err := checkMember(m.conversationRepo, conversationId, userId); err != nil {
+			return nil, err
+		}
+		query.ConversationIds = []string{conversationId}
+	} else {
+		conversations, err := m.conversationRepo.GetUserConversations(userId)
+		if err != nil {
+			return nil, err
+		}
+		for _, conversation := range conversations {
+			query.ConversationIds = append(query.ConversationIds, conversation.Id)
+		}
+	}
+
+	page, err := m.repo.SearchMessages(query)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range page.Results {
+		result.Message.BodyHtml = renderMarkdown(result.Message.Body)
+	}
+	return page, nil
 }
 
 func (m *MessangerService) UpdateMessage(id, body, user_id string) (*domain.Message, error) {
-	return m.repo.UpdateMessage(id, body, user_id)
+	if err := checkBodyLength(body); err != nil {
+		return nil, err
+	}
+
+	current, err := m.repo.GetOneMessage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.UserId != user_id {
+		return nil, errors.New("message not found")
+	}
+
+	if current.DeletedAt != nil {
+		return nil, errors.New("cannot edit a deleted message")
+	}
+
+	if current.Type != "" && current.Type != domain.MessageText {
+		return nil, errors.New("only text messages can be edited")
+	}
+
+	if current.Body == body {
+		return current, nil
+	}
+
+	editedAt := time.Now().UTC()
+	revision := domain.MessageRevision{
+		Id:        uuid.New().String(),
+		MessageId: current.Id,
+		Body:      current.Body,
+		EditorId:  user_id,
+		EditedAt:  editedAt,
+	}
+	if err := m.revisionRepo.AddRevision(revision); err != nil {
+		return nil, err
+	}
+
+	// revisions and messages may live in different stores, so a failed edit
+	// takes its revision back instead of sharing a transaction
+	message, err := m.repo.UpdateMessage(id, body, user_id, editedAt)
+	if err != nil {
+		if deleteErr := m.revisionRepo.DeleteRevision(revision.Id); deleteErr != nil {
+			log.Printf("delete revision %s: %v", revision.Id, deleteErr)
+		}
+		return nil, err
+	}
+
+	// the edit is already stored, so failing to update its mentions is only logged
+	if message.ForwardedFromId == "" {
+		previous, err := m.mentionRepo.GetMentions([]string{message.Id})
+		if err == nil {
+			err = m.saveMentions(message, previous)
+		}
+		if err != nil {
+			log.Printf("save mentions of message %s: %v", message.Id, err)
+		}
+	}
+	m.enqueueUnfurl(message, true)
+
+	message.BodyHtml = renderMarkdown(message.Body)
+	m.publish(domain.EventMessageUpdated, message.ConversationId, message)
+	return message, nil
+}
+
+func (m *MessangerService) GetHistory(userId, id string) ([]*domain.MessageRevision, error) {
+	message, err := m.getMessage(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.DeletedAt != nil {
+		return nil, errors.New("message is deleted")
+	}
+	return m.revisionRepo.GetRevisions(message.Id)
 }
 
 func (m *MessangerService) DeleteMessage(id, user_id string) error {
-	return m.repo.DeleteMessage(id, user_id)
+	message, err := m.repo.GetOneMessage(id)
+	if err != nil {
+		return err
+	}
+
+	if message.DeletedAt != nil {
+		return errors.New("message is already deleted")
+	}
+
+	if err := m.repo.DeleteMessage(id, user_id, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	m.publish(domain.EventMessageDeleted, message.ConversationId, map[string]string{"_id": message.Id})
+	return nil
+}
+
+func (m *MessangerService) RestoreMessage(id, user_id string) (*domain.Message, error) {
+	deletedSince := time.Now().UTC().Add(-m.restoreWindow)
+	if err := m.repo.RestoreMessage(id, user_id, deletedSince); err != nil {
+		return nil, err
+	}
+
+	message, err := m.repo.GetOneMessage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	message.BodyHtml = renderMarkdown(message.Body)
+	m.publish(domain.EventMessageRestored, message.ConversationId, message)
+	return message, nil
+}
+
+func (m *MessangerService) PurgeDeletedMessages() (int, error) {
+	ids, err := m.repo.PurgeDeletedMessages(time.Now().UTC().Add(-m.retention))
+	if err != nil {
+		return 0, err
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if err := m.deleteMessageData(ids); err != nil {
+		return len(ids), err
+	}
+	return len(ids), nil
+}
+
+// ExpireMessages removes messages whose TTL has passed, along with everything
+// attached to them, and tells the conversation to drop them.
+func (m *MessangerService) ExpireMessages() (int, error) {
+	messages, err := m.repo.ExpireMessages(time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.Id)
+	}
+
+	if err := m.deleteMessageData(ids); err != nil {
+		return len(ids), err
+	}
+
+	for _, message := range messages {
+		m.publish(domain.EventMessageExpired, message.ConversationId, map[string]string{
+			"_id":             message.Id,
+			"conversation_id": message.ConversationId,
+		})
+	}
+	return len(ids), nil
+}
+
+func (m *MessangerService) StartExpiry(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := m.ExpireMessages(); err != nil {
+				log.Printf("expire messages: %v", err)
+			} else if count > 0 {
+				log.Printf("expired %d messages", count)
+			}
+		}
+	}()
+}
+
+func (m *MessangerService) deleteMessageData(ids []string) error {
+	attachments, err := m.attachmentRepo.GetAttachments(ids)
+	if err != nil {
+		return err
+	}
+	if err := m.deleteThumbnails(attachments); err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if err := m.blobs.Delete(attachment.StorageKey); err != nil {
+			return err
+		}
+	}
+	if err := m.attachmentRepo.DeleteAttachments(ids); err != nil {
+		return err
+	}
+
+	if err := m.mentionRepo.DeleteMentions(ids); err != nil {
+		return err
+	}
+	if err := m.pinRepo.DeletePins(ids); err != nil {
+		return err
+	}
+	if err := m.starRepo.DeleteStars(ids); err != nil {
+		return err
+	}
+	if err := m.pollRepo.DeletePolls(ids); err != nil {
+		return err
+	}
+	if err := m.linkPreviewRepo.DeleteLinkPreviews(ids); err != nil {
+		return err
+	}
+	if m.webhooks != nil {
+		if err := m.webhooks.DropMessageDeliveries(ids); err != nil {
+			return err
+		}
+	}
+	if err := m.reactionRepo.DeleteReactions(ids); err != nil {
+		return err
+	}
+	if err := m.revisionRepo.DeleteRevisions(ids); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *MessangerService) SetPreviews(previews *PreviewService) {
+	m.previews = previews
+}
+
+func (m *MessangerService) StartPurge(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := m.PurgeDeletedMessages(); err != nil {
+				log.Printf("purge deleted messages: %v", err)
+			} else if count > 0 {
+				log.Printf("purged %d deleted messages", count)
+			}
+		}
+	}()
+}
+
+func (m *MessangerService) getMessage(userId, id string) (*domain.Message, error) {
+	message, err := m.repo.GetOneMessage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkMember(m.conversationRepo, message.ConversationId, userId); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// visibleMessages loads the given messages in one query and keeps those that are
+// not deleted and belong to a conversation the user is a member of.
+func (m *MessangerService) visibleMessages(userId string, ids []string) (map[string]*domain.Message, error) {
+	visible := make(map[string]*domain.Message)
+	if len(ids) == 0 {
+		return visible, nil
+	}
+
+	messages, err := m.repo.GetMessages(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	conversations, err := m.conversationRepo.GetUserConversations(userId)
+	if err != nil {
+		return nil, err
+	}
+	member := make(map[string]bool, len(conversations))
+	for _, conversation := range conversations {
+		member[conversation.Id] = true
+	}
+
+	for _, message := range messages {
+		if message.DeletedAt == nil && member[message.ConversationId] {
+			visible[message.Id] = message
+		}
+	}
+	return visible, nil
+}
+
+func (m *MessangerService) render(userId string, messages []*domain.Message) error {
+	if err := m.attachReactions(userId, messages); err != nil {
+		return err
+	}
+
+	if err := m.attachAttachments(messages); err != nil {
+		return err
+	}
+
+	if err := m.attachMentions(messages); err != nil {
+		return err
+	}
+
+	if err := m.attachQuotes(messages); err != nil {
+		return err
+	}
+
+	if err := m.attachPolls(userId, messages); err != nil {
+		return err
+	}
+
+	if err := m.attachLinkPreviews(messages); err != nil {
+		return err
+	}
+
+	if err := m.attachDelivery(userId, messages); err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		if message.Type == "" {
+			message.Type = domain.MessageText
+		}
+		message.BodyHtml = renderMarkdown(message.Body)
+		if message.DeletedAt != nil {
+			message.Body = domain.DeletedMessageBody
+			message.Reactions = nil
+			message.Attachments = nil
+			message.Mentions = nil
+			message.Quote = nil
+			message.Poll = nil
+			message.Previews = nil
+			message.BodyHtml = ""
+			message.Delivery = nil
+		}
+	}
+	return nil
+}
+
+func (m *MessangerService) attachQuotes(messages []*domain.Message) error {
+	var ids []string
+	for _, message := range messages {
+		if message.QuoteId != "" {
+			ids = append(ids, message.QuoteId)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	quoted, err := m.repo.GetMessages(ids)
+	if err != nil {
+		return err
+	}
+
+	byId := make(map[string]*domain.Message, len(quoted))
+	for _, message := range quoted {
+		byId[message.Id] = message
+	}
+
+	for _, message := range messages {
+		if message.QuoteId == "" {
+			continue
+		}
+
+		source, ok := byId[message.QuoteId]
+		if !ok {
+			message.Quote = &domain.MessageQuote{Id: message.QuoteId, Body: domain.DeletedMessageBody, Deleted: true}
+			continue
+		}
+
+		message.Quote = &domain.MessageQuote{
+			Id:             source.Id,
+			ConversationId: source.ConversationId,
+			UserId:         source.UserId,
+			Body:           source.Body,
+			CreatedAt:      source.CreatedAt,
+		}
+		if source.DeletedAt != nil {
+			message.Quote.Body = domain.DeletedMessageBody
+			message.Quote.Deleted = true
+		}
+	}
+	return nil
+}
+
+func (m *MessangerService) attachAttachments(messages []*domain.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.Id)
+	}
+
+	attachments, err := m.attachmentRepo.GetAttachments(ids)
+	if err != nil {
+		return err
+	}
+
+	attachmentIds := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		attachmentIds = append(attachmentIds, attachment.Id)
+	}
+
+	thumbnails, err := m.attachmentRepo.GetThumbnails(attachmentIds)
+	if err != nil {
+		return err
+	}
+
+	byAttachment := make(map[string][]*domain.Thumbnail)
+	for _, thumbnail := range thumbnails {
+		byAttachment[thumbnail.AttachmentId] = append(byAttachment[thumbnail.AttachmentId], thumbnail)
+	}
+
+	byMessage := make(map[string][]*domain.Attachment)
+	for _, attachment := range attachments {
+		attachment.Thumbnails = byAttachment[attachment.Id]
+		byMessage[attachment.MessageId] = append(byMessage[attachment.MessageId], attachment)
+	}
+
+	for _, message := range messages {
+		message.Attachments = byMessage[message.Id]
+	}
+	return nil
+}
+
+func (m *MessangerService) storeUploads(userId string, message domain.Message, uploads []domain.Upload) ([]*domain.Attachment, error) {
+	var attachments []*domain.Attachment
+	for _, upload := range uploads {
+		attachment, err := m.storeUpload(userId, message, upload)
+		if err != nil {
+			m.discardBlobs(attachments)
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+
+	if err := m.attachmentRepo.AddAttachments(attachments); err != nil {
+		m.discardBlobs(attachments)
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (m *MessangerService) storeUpload(userId string, message domain.Message, upload domain.Upload) (*domain.Attachment, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(upload.Content, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, errors.New(fmt.Sprintf("attachment not readable: %v", err))
+	}
+	head = head[:n]
+
+	fileName := filepath.Base(upload.FileName)
+	mimeType, err := checkMimeType(fileName, head)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := &domain.Attachment{
+		Id:             uuid.New().String(),
+		MessageId:      message.Id,
+		ConversationId: message.ConversationId,
+		UserId:         userId,
+		FileName:       fileName,
+		MimeType:       mimeType,
+		Size:           upload.Size,
+		CreatedAt:      message.CreatedAt,
+	}
+	attachment.StorageKey = "attachments/" + message.ConversationId + "/" + attachment.Id
+	if m.previews != nil && previewable(mimeType) {
+		attachment.PreviewStatus = domain.PreviewPending
+		m.previews.claim(attachment)
+	}
+
+	hash := sha256.New()
+	content := io.TeeReader(io.MultiReader(bytes.NewReader(head), upload.Content), hash)
+	if err := m.blobs.Put(attachment.StorageKey, content, upload.Size, attachment.MimeType); err != nil {
+		return nil, err
+	}
+
+	attachment.Checksum = "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	return attachment, nil
+}
+
+func (m *MessangerService) deleteThumbnails(attachments []*domain.Attachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	attachmentIds := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		attachmentIds = append(attachmentIds, attachment.Id)
+	}
+
+	thumbnails, err := m.attachmentRepo.GetThumbnails(attachmentIds)
+	if err != nil {
+		return err
+	}
+	for _, thumbnail := range thumbnails {
+		if err := m.blobs.Delete(thumbnail.StorageKey); err != nil {
+			return err
+		}
+	}
+	return m.attachmentRepo.DeleteThumbnails(attachmentIds)
+}
+
+// checkMimeType sniffs the real type of an upload and rejects unsupported types and
+// files whose extension claims a different type than their content.
+func checkMimeType(fileName string, head []byte) (string, error) {
+	mimeType := http.DetectContentType(head)
+
+	extensions, ok := allowedMimeTypes[strings.SplitN(mimeType, ";", 2)[0]]
+	if !ok {
+		return "", errors.New(fmt.Sprintf("unsupported file type: %s", mimeType))
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, allowed := range extensions {
+		if ext == allowed {
+			return mimeType, nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("file extension %q does not match its content type %s", ext, mimeType))
+}
+
+func (m *MessangerService) discardAttachments(messageId string, attachments []*domain.Attachment) {
+	if len(attachments) == 0 {
+		return
+	}
+	_ = m.attachmentRepo.DeleteAttachments([]string{messageId})
+	m.discardBlobs(attachments)
+}
+
+func (m *MessangerService) discardBlobs(attachments []*domain.Attachment) {
+	for _, attachment := range attachments {
+		_ = m.blobs.Delete(attachment.StorageKey)
+	}
+}
+
+func (m *MessangerService) publish(eventType, conversationId string, data interface{}) {
+	publishToConversation(m.events, m.conversationRepo, eventType, conversationId, data)
+
+	if m.webhooks != nil {
+		m.webhooks.Dispatch(eventType, conversationId, data)
+	}
+}
+
+func (m *MessangerService) publishToUser(eventType, conversationId, userId string, data interface{}) {
+	if m.events == nil {
+		return
+	}
+
+	m.events.Publish(domain.Event{
+		Type:           eventType,
+		ConversationId: conversationId,
+		Data:           data,
+		UserIds:        []string{userId},
+		CreatedAt:      time.Now().UTC(),
+	})
+}
+
+func (m *MessangerService) attachReactions(userId string, messages []*domain.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.Id)
+	}
+
+	counted, err := m.reactionRepo.GetReactionSummaries(ids, userId)
+	if err != nil {
+		return err
+	}
+
+	summaries := make(map[string][]domain.ReactionSummary)
+	for _, summary := range counted {
+		summaries[summary.MessageId] = append(summaries[summary.MessageId], *summary)
+	}
+
+	for _, message := range messages {
+		message.Reactions = summaries[message.Id]
+	}
+	return nil
+}
+
+func validateEmoji(emoji string) (string, error) {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" {
+		return "", errors.New("emoji is required")
+	}
+	if len(emoji) > 64 || strings.ContainsAny(emoji, " \t\n") {
+		return "", errors.New("emoji is not valid")
+	}
+	return emoji, nil
 }
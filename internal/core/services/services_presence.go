@@ -0,0 +1,314 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"messenger/internal/core/domain"
+	"messenger/internal/core/ports"
+)
+
+const (
+	DefaultPresenceTtl = 90 * time.Second
+	DefaultTypingTtl   = 6 * time.Second
+	MaxPresenceLookup  = 100
+)
+
+type PresenceService struct {
+	store            ports.PresenceStore
+	userRepo         ports.UserRepository
+	conversationRepo ports.ConversationRepository
+	events           ports.EventPublisher
+	sessionTtl       time.Duration
+	typingTtl        time.Duration
+}
+
+func NewPresenceService(store ports.PresenceStore, userRepo ports.UserRepository, conversationRepo ports.ConversationRepository, events ports.EventPublisher) *PresenceService {
+	return &PresenceService{
+		store:            store,
+		userRepo:         userRepo,
+		conversationRepo: conversationRepo,
+		events:           events,
+		sessionTtl:       DefaultPresenceTtl,
+		typingTtl:        DefaultTypingTtl,
+	}
+}
+
+func (p *PresenceService) SetExpiry(sessionTtl, typingTtl time.Duration) {
+	p.sessionTtl = sessionTtl
+	p.typingTtl = typingTtl
+}
+
+// Heartbeat keeps a session alive with the status it already has, marking new
+// sessions online.
+func (p *PresenceService) Heartbeat(userId, sessionId string) error {
+	status := domain.PresenceOnline
+	sessions, err := p.store.GetSessions([]string{userId}, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.SessionId == sessionId {
+			status = session.Status
+		}
+	}
+	return p.SetStatus(userId, sessionId, status)
+}
+
+func (p *PresenceService) SetStatus(userId, sessionId, status string) error {
+	if sessionId == "" {
+		return errors.New("session_id is required")
+	}
+
+	switch status {
+	case domain.PresenceOnline, domain.PresenceAway:
+	case domain.PresenceOffline:
+		return p.Disconnect(userId, sessionId)
+	default:
+		return errors.New("status must be online, away or offline")
+	}
+
+	return p.update(userId, func() error {
+		return p.store.SetSession(domain.PresenceSession{
+			UserId:    userId,
+			SessionId: sessionId,
+			Status:    status,
+			ExpiresAt: time.Now().UTC().Add(p.sessionTtl),
+		})
+	})
+}
+
+func (p *PresenceService) Disconnect(userId, sessionId string) error {
+	return p.update(userId, func() error {
+		return p.store.RemoveSession(userId, sessionId)
+	})
+}
+
+// update applies a session change and announces the user's presence when it moved.
+func (p *PresenceService) update(userId string, change func() error) error {
+	before, err := p.status(userId)
+	if err != nil {
+		return err
+	}
+
+	if err := change(); err != nil {
+		return err
+	}
+
+	after, err := p.status(userId)
+	if err != nil {
+		return err
+	}
+
+	if before != after {
+		p.announce(userId, after)
+	}
+	return nil
+}
+
+func (p *PresenceService) announce(userId, status string) {
+	presence := &domain.Presence{UserId: userId, Status: status}
+	if status == domain.PresenceOffline {
+		lastSeenAt := time.Now().UTC()
+		if err := p.userRepo.SetLastSeen(userId, lastSeenAt); err != nil {
+			log.Printf("last seen of %s: %v", userId, err)
+		}
+		presence.LastSeenAt = &lastSeenAt
+	}
+
+	contacts, err := p.contacts(userId)
+	if err != nil || p.events == nil {
+		return
+	}
+
+	p.events.Publish(domain.Event{
+		Type:      domain.EventPresenceUpdated,
+		Data:      presence,
+		UserIds:   contacts,
+		CreatedAt: time.Now().UTC(),
+		Ephemeral: true,
+	})
+}
+
+func (p *PresenceService) status(userId string) (string, error) {
+	sessions, err := p.store.GetSessions([]string{userId}, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	return presenceStatus(sessions), nil
+}
+
+// GetPresences returns the presence of the given users that share a conversation
+// with the caller; anyone else is left out.
+func (p *PresenceService) GetPresences(userId string, userIds []string) ([]*domain.Presence, error) {
+	if len(userIds) > MaxPresenceLookup {
+		return nil, errors.New("too many users requested")
+	}
+
+	contacts, err := p.contacts(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(contacts))
+	for _, id := range contacts {
+		known[id] = true
+	}
+
+	var visible []string
+	for _, id := range userIds {
+		if known[id] {
+			visible = append(visible, id)
+		}
+	}
+
+	users, err := p.userRepo.GetUsers(visible)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := p.store.GetSessions(visible, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[string][]*domain.PresenceSession)
+	for _, session := range sessions {
+		byUser[session.UserId] = append(byUser[session.UserId], session)
+	}
+
+	presences := make([]*domain.Presence, 0, len(users))
+	for _, user := range users {
+		presences = append(presences, &domain.Presence{
+			UserId:     user.Id,
+			Status:     presenceStatus(byUser[user.Id]),
+			LastSeenAt: user.LastSeenAt,
+		})
+	}
+	return presences, nil
+}
+
+func (p *PresenceService) StartTyping(userId, conversationId string) error {
+	if err := checkMember(p.conversationRepo, conversationId, userId); err != nil {
+		return err
+	}
+
+	typing := domain.Typing{
+		ConversationId: conversationId,
+		UserId:         userId,
+		ExpiresAt:      time.Now().UTC().Add(p.typingTtl),
+	}
+
+	started, err := p.store.SetTyping(typing)
+	if err != nil {
+		return err
+	}
+
+	if started {
+		publishEphemeralToConversation(p.events, p.conversationRepo, domain.EventTypingStarted, conversationId, typing)
+	}
+	return nil
+}
+
+func (p *PresenceService) StopTyping(userId, conversationId string) error {
+	if err := checkMember(p.conversationRepo, conversationId, userId); err != nil {
+		return err
+	}
+
+	stopped, err := p.store.RemoveTyping(conversationId, userId)
+	if err != nil {
+		return err
+	}
+
+	if stopped {
+		publishEphemeralToConversation(p.events, p.conversationRepo, domain.EventTypingStopped, conversationId, domain.Typing{
+			ConversationId: conversationId,
+			UserId:         userId,
+			ExpiresAt:      time.Now().UTC(),
+		})
+	}
+	return nil
+}
+
+func (p *PresenceService) GetTyping(userId, conversationId string) ([]*domain.Typing, error) {
+	if err := checkMember(p.conversationRepo, conversationId, userId); err != nil {
+		return nil, err
+	}
+	return p.store.GetTyping(conversationId, time.Now().UTC())
+}
+
+// Sweep drops expired sessions and typing signals and announces what they changed.
+func (p *PresenceService) Sweep() error {
+	now := time.Now().UTC()
+
+	sessions, err := p.store.ExpireSessions(now)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, session := range sessions {
+		if seen[session.UserId] {
+			continue
+		}
+		seen[session.UserId] = true
+
+		status, err := p.status(session.UserId)
+		if err != nil {
+			return err
+		}
+		p.announce(session.UserId, status)
+	}
+
+	typing, err := p.store.ExpireTyping(now)
+	if err != nil {
+		return err
+	}
+	for _, entry := range typing {
+		publishEphemeralToConversation(p.events, p.conversationRepo, domain.EventTypingStopped, entry.ConversationId, entry)
+	}
+	return nil
+}
+
+func (p *PresenceService) StartSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := p.Sweep(); err != nil {
+				log.Printf("sweep presence: %v", err)
+			}
+		}
+	}()
+}
+
+// contacts lists the user and everyone sharing a conversation with them.
+func (p *PresenceService) contacts(userId string) ([]string, error) {
+	ids, err := p.conversationRepo.GetContactIds(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := []string{userId}
+	for _, id := range ids {
+		if id != userId {
+			contacts = append(contacts, id)
+		}
+	}
+	return contacts, nil
+}
+
+func presenceStatus(sessions []*domain.PresenceSession) string {
+	status := domain.PresenceOffline
+	for _, session := range sessions {
+		if session.Status == domain.PresenceOnline {
+			return domain.PresenceOnline
+		}
+		if session.Status == domain.PresenceAway {
+			status = domain.PresenceAway
+		}
+	}
+	return status
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"messenger/internal/core/domain"
+	"messenger/internal/core/ports"
+)
+
+const (
+	previewQueueSize       = 1000
+	previewRequeueInterval = time.Minute
+	previewLease           = 10 * time.Minute
+)
+
+var ThumbnailSizes = []domain.ThumbnailSize{
+	{Name: "small", MaxSide: 96},
+	{Name: "medium", MaxSide: 320},
+	{Name: "large", MaxSide: 1280},
+}
+
+type PreviewService struct {
+	repo             ports.AttachmentRepository
+	blobs            ports.BlobStorage
+	thumbnailer      ports.Thumbnailer
+	conversationRepo ports.ConversationRepository
+	events           ports.EventPublisher
+	instanceId       string
+	jobs             chan *domain.Attachment
+	mu               sync.Mutex
+	queued           map[string]bool
+}
+
+func NewPreviewService(repo ports.AttachmentRepository, blobs ports.BlobStorage, thumbnailer ports.Thumbnailer, conversationRepo ports.ConversationRepository, events ports.EventPublisher) *PreviewService {
+	return &PreviewService{
+		repo:             repo,
+		blobs:            blobs,
+		thumbnailer:      thumbnailer,
+		conversationRepo: conversationRepo,
+		events:           events,
+		instanceId:       uuid.New().String(),
+		jobs:             make(chan *domain.Attachment, previewQueueSize),
+		queued:           make(map[string]bool),
+	}
+}
+
+// Start runs the worker pool and keeps requeueing pending previews, both those
+// left by a previous run and those that did not fit in a full queue. Pending
+// previews are leased per instance, so running several is safe.
+func (p *PreviewService) Start(workers int) {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for attachment := range p.jobs {
+				p.generate(attachment)
+
+				p.mu.Lock()
+				delete(p.queued, attachment.Id)
+				p.mu.Unlock()
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(previewRequeueInterval)
+		defer ticker.Stop()
+
+		for ; true; <-ticker.C {
+			p.requeuePending()
+		}
+	}()
+}
+
+// claim leases a new attachment to this instance before it is stored, so the
+// other instances leave its preview to the worker it is enqueued on.
+func (p *PreviewService) claim(attachment *domain.Attachment) {
+	claimedUntil := time.Now().UTC().Add(previewLease)
+	attachment.ClaimedBy = p.instanceId
+	attachment.ClaimedUntil = &claimedUntil
+}
+
+// Enqueue never blocks: when the queue is full the attachment stays pending
+// and is picked up again by the requeue loop once its lease runs out.
+func (p *PreviewService) Enqueue(attachment *domain.Attachment) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.queued[attachment.Id] {
+		return
+	}
+
+	select {
+	case p.jobs <- attachment:
+		p.queued[attachment.Id] = true
+	default:
+	}
+}
+
+// requeuePending only claims as many previews as the queue has room for, so
+// claimed previews are not left waiting behind a full queue.
+func (p *PreviewService) requeuePending() {
+	room := cap(p.jobs) - len(p.jobs)
+	if room <= 0 {
+		return
+	}
+
+	pending, err := p.repo.ClaimPendingPreviews(time.Now().UTC(), p.instanceId, previewLease, room)
+	if err != nil {
+		log.Printf("claim pending previews: %v", err)
+	}
+	for _, attachment := range pending {
+		p.Enqueue(attachment)
+	}
+}
+
+func (p *PreviewService) generate(attachment *domain.Attachment) {
+	thumbnails, err := p.render(attachment)
+	if err != nil {
+		log.Printf("preview attachment %s: %v", attachment.Id, err)
+		if err := p.repo.SetPreviewStatus(attachment.Id, domain.PreviewFailed); err != nil {
+			log.Printf("preview attachment %s: %v", attachment.Id, err)
+		}
+		return
+	}
+
+	if err := p.repo.SetPreviewStatus(attachment.Id, domain.PreviewReady); err != nil {
+		log.Printf("preview attachment %s: %v", attachment.Id, err)
+		return
+	}
+
+	attachment.PreviewStatus = domain.PreviewReady
+	attachment.Thumbnails = thumbnails
+	publishToConversation(p.events, p.conversationRepo, domain.EventAttachmentPreviewed, attachment.ConversationId, attachment)
+}
+
+func (p *PreviewService) render(attachment *domain.Attachment) ([]*domain.Thumbnail, error) {
+	content, err := p.blobs.Get(attachment.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	thumbnails, err := p.thumbnailer.Thumbnails(content, ThumbnailSizes)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now().UTC()
+	for _, thumbnail := range thumbnails {
+		thumbnail.AttachmentId = attachment.Id
+		thumbnail.StorageKey = thumbnailKey(attachment, thumbnail.Size)
+		thumbnail.CreatedAt = createdAt
+
+		if err := p.blobs.Put(thumbnail.StorageKey, bytes.NewReader(thumbnail.Content), int64(len(thumbnail.Content)), thumbnail.MimeType); err != nil {
+			return nil, err
+		}
+		thumbnail.Content = nil
+	}
+
+	if err := p.repo.AddThumbnails(thumbnails); err != nil {
+		return nil, err
+	}
+	return thumbnails, nil
+}
+
+// thumbnails sit next to the original blob
+func thumbnailKey(attachment *domain.Attachment, size string) string {
+	return attachment.StorageKey + ".thumb-" + size
+}
+
+func previewable(mimeType string) bool {
+	switch strings.SplitN(mimeType, ";", 2)[0] {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"messenger/internal/core/domain"
+	"messenger/internal/core/ports"
+)
+
+type ReadService struct {
+	repo             ports.ReadMarkerRepository
+	messageRepo      ports.MessangerRepository
+	conversationRepo ports.ConversationRepository
+	events           ports.EventPublisher
+}
+
+func NewReadService(repo ports.ReadMarkerRepository, messageRepo ports.MessangerRepository, conversationRepo ports.ConversationRepository, events ports.EventPublisher) *ReadService {
+	return &ReadService{
+		repo:             repo,
+		messageRepo:      messageRepo,
+		conversationRepo: conversationRepo,
+		events:           events,
+	}
+}
+
+func (r *ReadService) MarkRead(userId, conversationId, messageId string) error {
+	if err := checkMember(r.conversationRepo, conversationId, userId); err != nil {
+		return err
+	}
+
+	message, err := r.messageRepo.GetOneMessage(messageId)
+	if err != nil {
+		return err
+	}
+
+	if message.ConversationId != conversationId {
+		return errors.New("message does not belong to this conversation")
+	}
+
+	marker := domain.ReadMarker{
+		ConversationId:    conversationId,
+		UserId:            userId,
+		LastReadMessageId: message.Id,
+		LastReadAt:        message.CreatedAt,
+		UpdatedAt:         time.Now().UTC(),
+	}
+
+	advanced, err := r.repo.SetReadMarker(marker)
+	if err != nil {
+		return err
+	}
+
+	if advanced {
+		if err := r.messageRepo.StartReadExpiry(conversationId, userId, message.CreatedAt, marker.UpdatedAt); err != nil {
+			return err
+		}
+		publishToConversation(r.events, r.conversationRepo, domain.EventReadUpdated, conversationId, marker)
+	}
+	return nil
+}
+
+func (r *ReadService) GetReadMarkers(userId, conversationId string) ([]*domain.ReadMarker, error) {
+	if err := checkMember(r.conversationRepo, conversationId, userId); err != nil {
+		return nil, err
+	}
+	return r.repo.GetReadMarkers(conversationId)
+}
+
+func (r *ReadService) GetUnreadCounts(userId string) ([]*domain.UnreadCount, error) {
+	conversations, err := r.conversationRepo.GetUserConversations(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	markers, err := r.repo.GetUserReadMarkers(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	lastRead := make(map[string]time.Time, len(markers))
+	for _, marker := range markers {
+		lastRead[marker.ConversationId] = marker.LastReadAt
+	}
+
+	since := make(map[string]time.Time, len(conversations))
+	for _, conversation := range conversations {
+		since[conversation.Id] = lastRead[conversation.Id]
+	}
+
+	unread, err := r.messageRepo.CountMessagesSince(userId, since)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]*domain.UnreadCount, 0, len(conversations))
+	for _, conversation := range conversations {
+		counts = append(counts, &domain.UnreadCount{
+			ConversationId: conversation.Id,
+			Unread:         unread[conversation.Id],
+		})
+	}
+	return counts, nil
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"log"
+	"net/url"
+	"regexp"
+	"time"
+
+	"messenger/internal/core/domain"
+	"messenger/internal/core/ports"
+)
+
+const (
+	MaxLinkPreviews = 3
+	unfurlQueueSize = 1000
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+type UnfurlService struct {
+	repo             ports.LinkPreviewRepository
+	unfurler         ports.Unfurler
+	messageRepo      ports.MessangerRepository
+	conversationRepo ports.ConversationRepository
+	events           ports.EventPublisher
+	jobs             chan *domain.Message
+}
+
+func NewUnfurlService(repo ports.LinkPreviewRepository, unfurler ports.Unfurler, messageRepo ports.MessangerRepository, conversationRepo ports.ConversationRepository, events ports.EventPublisher) *UnfurlService {
+	return &UnfurlService{
+		repo:             repo,
+		unfurler:         unfurler,
+		messageRepo:      messageRepo,
+		conversationRepo: conversationRepo,
+		events:           events,
+		jobs:             make(chan *domain.Message, unfurlQueueSize),
+	}
+}
+
+func (u *UnfurlService) Start(workers int) {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for message := range u.jobs {
+				u.unfurl(message)
+			}
+		}()
+	}
+}
+
+func (u *UnfurlService) Enqueue(message *domain.Message) {
+	job := &domain.Message{Id: message.Id, ConversationId: message.ConversationId, Body: message.Body}
+	// previews are best effort, so a full queue drops the job instead of
+	// parking a goroutine per message
+	select {
+	case u.jobs <- job:
+	default:
+		log.Printf("unfurl queue full, no preview for %s", message.Id)
+	}
+}
+
+func (u *UnfurlService) unfurl(message *domain.Message) {
+	links := extractLinks(message.Body)
+
+	previews := make([]*domain.LinkPreview, 0, len(links))
+	for _, link := range links {
+		preview, err := u.unfurler.Unfurl(link)
+		if err != nil {
+			log.Printf("unfurl %s: %v", link, err)
+			continue
+		}
+
+		preview.MessageId = message.Id
+		preview.Url = link
+		preview.Position = len(previews)
+		preview.CreatedAt = time.Now().UTC()
+		previews = append(previews, preview)
+	}
+
+	// the message may have been edited or deleted while the links were fetched
+	current, err := u.messageRepo.GetOneMessage(message.Id)
+	if err != nil || current.DeletedAt != nil || current.Body != message.Body {
+		return
+	}
+
+	if len(previews) == 0 {
+		existing, err := u.repo.GetLinkPreviews([]string{message.Id})
+		if err != nil || len(existing) == 0 {
+			return
+		}
+	}
+
+	if err := u.repo.SetLinkPreviews(message.Id, previews); err != nil {
+		log.Printf("unfurl message %s: %v", message.Id, err)
+		return
+	}
+
+	publishToConversation(u.events, u.conversationRepo, domain.EventLinkPreviewed, message.ConversationId, map[string]interface{}{
+		"_id":             message.Id,
+		"conversation_id": message.ConversationId,
+		"previews":        previews,
+	})
+}
+
+// extractLinks returns the first distinct http(s) links of a body, without the
+// punctuation that usually trails a link in a sentence.
+func extractLinks(body string) []string {
+	var links []string
+	seen := make(map[string]bool)
+
+	for _, match := range urlPattern.FindAllString(body, -1) {
+		link := trimLink(match)
+
+		target, err := url.Parse(link)
+		if err != nil || target.Hostname() == "" || seen[link] {
+			continue
+		}
+
+		seen[link] = true
+		links = append(links, link)
+		if len(links) == MaxLinkPreviews {
+			break
+		}
+	}
+	return links
+}
+
+func (m *MessangerService) SetUnfurls(unfurls *UnfurlService) {
+	m.unfurls = unfurls
+}
+
+func (m *MessangerService) enqueueUnfurl(message *domain.Message, edited bool) {
+	if m.unfurls == nil || (!edited && len(extractLinks(message.Body)) == 0) {
+		return
+	}
+	m.unfurls.Enqueue(message)
+}
+
+func (m *MessangerService) attachLinkPreviews(messages []*domain.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.Id)
+	}
+
+	previews, err := m.linkPreviewRepo.GetLinkPreviews(ids)
+	if err != nil {
+		return err
+	}
+
+	byMessage := make(map[string][]*domain.LinkPreview)
+	for _, preview := range previews {
+		byMessage[preview.MessageId] = append(byMessage[preview.MessageId], preview)
+	}
+
+	for _, message := range messages {
+		message.Previews = byMessage[message.Id]
+	}
+	return nil
+}
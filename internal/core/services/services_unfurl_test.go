@@ -0,0 +1,114 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"messenger/internal/adapters/unfurl"
+	"messenger/internal/core/domain"
+	"messenger/internal/core/ports"
+)
+
+type stubMessageRepository struct {
+	ports.MessangerRepository
+	message *domain.Message
+}
+
+func (s *stubMessageRepository) GetOneMessage(id string) (*domain.Message, error) {
+	return s.message, nil
+}
+
+type stubLinkPreviewRepository struct {
+	ports.LinkPreviewRepository
+	saved map[string][]*domain.LinkPreview
+}
+
+func (s *stubLinkPreviewRepository) SetLinkPreviews(messageId string, previews []*domain.LinkPreview) error {
+	s.saved[messageId] = previews
+	return nil
+}
+
+func (s *stubLinkPreviewRepository) GetLinkPreviews(messageIds []string) ([]*domain.LinkPreview, error) {
+	var previews []*domain.LinkPreview
+	for _, id := range messageIds {
+		previews = append(previews, s.saved[id]...)
+	}
+	return previews, nil
+}
+
+func TestExtractLinks(t *testing.T) {
+	tests := []struct {
+		body  string
+		links []string
+	}{
+		{"no links here", nil},
+		{"see https://example.com.", []string{"https://example.com"}},
+		{"(https://example.com/a)", []string{"https://example.com/a"}},
+		{"https://en.wikipedia.org/wiki/Go_(language)!", []string{"https://en.wikipedia.org/wiki/Go_(language)"}},
+		{`<a href="https://example.com/x">`, []string{"https://example.com/x"}},
+		{"https://example.com https://example.com", []string{"https://example.com"}},
+		{"ftp://example.com and http://", nil},
+		{
+			"https://a.example https://b.example https://c.example https://d.example",
+			[]string{"https://a.example", "https://b.example", "https://c.example"},
+		},
+	}
+
+	for _, test := range tests {
+		if got := extractLinks(test.body); !reflect.DeepEqual(got, test.links) {
+			t.Errorf("extractLinks(%q) = %q, want %q", test.body, got, test.links)
+		}
+	}
+}
+
+func TestTrimLink(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com":              "https://example.com",
+		"https://example.com/.":            "https://example.com/",
+		"https://example.com/?q=1,":        "https://example.com/?q=1",
+		"https://example.com/a)":           "https://example.com/a",
+		"https://example.com/a(b)":         "https://example.com/a(b)",
+		"https://example.com/a(b))":        "https://example.com/a(b)",
+		"https://example.com/**":           "https://example.com/",
+		"https://example.com/page_\"'!?;:": "https://example.com/page",
+	}
+
+	for link, want := range tests {
+		if got := trimLink(link); got != want {
+			t.Errorf("trimLink(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+func TestUnfurlSavesPreviews(t *testing.T) {
+	message := &domain.Message{Id: "m1", ConversationId: "c1", Body: "read https://example.com/post"}
+	previews := &stubLinkPreviewRepository{saved: make(map[string][]*domain.LinkPreview)}
+	unfurler := unfurl.NewFakeUnfurler(map[string]*domain.LinkPreview{
+		"https://example.com/post": {Title: "A post"},
+	})
+
+	service := NewUnfurlService(previews, unfurler, &stubMessageRepository{message: message}, nil, nil)
+	service.unfurl(&domain.Message{Id: message.Id, ConversationId: message.ConversationId, Body: message.Body})
+
+	saved := previews.saved[message.Id]
+	if len(saved) != 1 || saved[0].Title != "A post" || saved[0].MessageId != message.Id {
+		t.Fatalf("saved previews = %+v, want the post preview", saved)
+	}
+}
+
+func TestUnfurlDropsPreviewsOfEditedMessage(t *testing.T) {
+	job := &domain.Message{Id: "m1", ConversationId: "c1", Body: "read https://example.com/old"}
+	current := &domain.Message{Id: "m1", ConversationId: "c1", Body: "read https://example.com/new"}
+	previews := &stubLinkPreviewRepository{saved: make(map[string][]*domain.LinkPreview)}
+	unfurler := unfurl.NewFakeUnfurler(nil)
+
+	service := NewUnfurlService(previews, unfurler, &stubMessageRepository{message: current}, nil, nil)
+	service.unfurl(job)
+
+	if calls := unfurler.Calls(); !reflect.DeepEqual(calls, []string{"https://example.com/old"}) {
+		t.Errorf("unfurled %q, want the old link", calls)
+	}
+	if saved, ok := previews.saved[job.Id]; ok {
+		t.Errorf("saved previews %+v for an edited message", saved)
+	}
+}
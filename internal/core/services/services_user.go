@@ -1,6 +1,9 @@
 package services
 
 import (
+	"errors"
+	"time"
+
 	"github.com/google/uuid"
 	"messenger/internal/adapters/repositories"
 	"messenger/internal/core/domain"
@@ -8,7 +11,8 @@ import (
 )
 
 type UserService struct {
-	repo ports.UserRepository
+	repo     ports.UserRepository
+	webhooks *WebhookService
 }
 
 func NewUserService(repo ports.UserRepository) *UserService {
@@ -18,16 +22,45 @@ func NewUserService(repo ports.UserRepository) *UserService {
 }
 
 func (u *UserService) RegisterUser(user domain.User) error {
+	user.Handle = normalizeHandle(user.Handle)
+	if user.Handle != "" {
+		if !handlePattern.MatchString(user.Handle) {
+			return errors.New("handle must be 2-32 letters, digits, '_', '.' or '-'")
+		}
+
+		taken, err := u.repo.GetUsersByHandles([]string{user.Handle})
+		if err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return errors.New("handle already taken")
+		}
+	}
+
 	user.Id = uuid.New().String()
-	return u.repo.RegisterUser(user)
+	user.CreatedAt = time.Now().UTC()
+	user.UpdatedAt = user.CreatedAt
+	user.LastSeenAt = nil
+	if err := u.repo.RegisterUser(user); err != nil {
+		return err
+	}
+
+	if u.webhooks != nil {
+		user.Password = ""
+		u.webhooks.Dispatch(domain.EventUserRegistered, "", user)
+	}
+	return nil
 }
 
 func (u *UserService) GetOneUser(id string) (*domain.User, error) {
 	return u.repo.GetOneUser(id)
 }
 
-func (u *UserService) GetAllUsers() ([]*domain.User, error) {
-	return u.repo.GetAllUsers()
+func (u *UserService) GetAllUsers(query domain.PageQuery) (*domain.UserPage, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+	return u.repo.GetAllUsers(query)
 }
 
 func (u *UserService) UpdateUser(id, email, password string) (*domain.User, error) {
@@ -0,0 +1,487 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"messenger/internal/core/domain"
+	"messenger/internal/core/ports"
+)
+
+const (
+	DefaultWebhookAttempts  = 8
+	DefaultWebhookBackoff   = 30 * time.Second
+	DefaultWebhookRetention = 7 * 24 * time.Hour
+	MaxWebhookDeliveries    = 100
+
+	maxWebhookBackoff      = 6 * time.Hour
+	webhookLease           = time.Minute
+	webhookBatchSize       = 50
+	webhookQueueSize       = 1000
+	webhookStoreAttempts   = 5
+	webhookStoreRetryDelay = time.Second
+)
+
+// WebhookEvents are the events a webhook can subscribe to.
+var WebhookEvents = []string{
+	domain.EventMessageCreated,
+	domain.EventMessageUpdated,
+	domain.EventMessageDeleted,
+	domain.EventMessageRestored,
+	domain.EventUserRegistered,
+}
+
+var ErrNotAdmin = errors.New("only admins can manage webhooks")
+
+type webhookPayload struct {
+	Id             string      `json:"id"`
+	Event          string      `json:"event"`
+	ConversationId string      `json:"conversation_id,omitempty"`
+	Data           interface{} `json:"data"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// webhookEvent is an event waiting in the queue to become deliveries.
+type webhookEvent struct {
+	eventType      string
+	conversationId string
+	messageId      string
+	data           json.RawMessage
+	createdAt      time.Time
+}
+
+type WebhookService struct {
+	repo       ports.WebhookRepository
+	sender     ports.WebhookSender
+	admins     map[string]bool
+	instanceId string
+	attempts   int
+	backoff    time.Duration
+	retention  time.Duration
+	queue      chan webhookEvent
+}
+
+func NewWebhookService(repo ports.WebhookRepository, sender ports.WebhookSender) *WebhookService {
+	return &WebhookService{
+		repo:       repo,
+		sender:     sender,
+		admins:     make(map[string]bool),
+		instanceId: uuid.New().String(),
+		attempts:   DefaultWebhookAttempts,
+		backoff:    DefaultWebhookBackoff,
+		retention:  DefaultWebhookRetention,
+		queue:      make(chan webhookEvent, webhookQueueSize),
+	}
+}
+
+func (w *WebhookService) SetAdmins(userIds []string) {
+	w.admins = make(map[string]bool, len(userIds))
+	for _, id := range userIds {
+		w.admins[id] = true
+	}
+}
+
+// SetRetryPolicy sets how often a delivery is tried before it is dead-lettered
+// and the delay before the first retry, which doubles on every further one.
+func (w *WebhookService) SetRetryPolicy(attempts int, backoff time.Duration) {
+	w.attempts = attempts
+	w.backoff = backoff
+}
+
+// SetRetention sets how long delivered and dead-lettered deliveries stay in the log.
+func (w *WebhookService) SetRetention(retention time.Duration) {
+	w.retention = retention
+}
+
+func (w *WebhookService) CreateWebhook(userId string, webhook domain.Webhook) (*domain.Webhook, error) {
+	if err := w.checkAdmin(userId); err != nil {
+		return nil, err
+	}
+
+	link, err := url.Parse(strings.TrimSpace(webhook.Url))
+	if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+		return nil, errors.New("url must be an absolute http or https URL")
+	}
+	if err := w.sender.CheckUrl(link.String()); err != nil {
+		return nil, err
+	}
+
+	var events []string
+	for _, event := range webhook.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return nil, errors.New(fmt.Sprintf("unknown webhook event: %s", event))
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return nil, errors.New("webhook needs at least one event")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	webhook.Id = uuid.New().String()
+	webhook.Url = link.String()
+	webhook.Events = events
+	webhook.Secret = hex.EncodeToString(secret)
+	webhook.CreatedBy = userId
+	webhook.CreatedAt = time.Now().UTC()
+	if err := w.repo.AddWebhook(webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (w *WebhookService) GetWebhooks(userId string) ([]*domain.Webhook, error) {
+	if err := w.checkAdmin(userId); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := w.repo.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	// the secret is only shown once, when the webhook is created
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (w *WebhookService) DeleteWebhook(userId, id string) error {
+	if err := w.checkAdmin(userId); err != nil {
+		return err
+	}
+	return w.repo.DeleteWebhook(id)
+}
+
+func (w *WebhookService) GetDeliveries(userId, webhookId, status string) ([]*domain.WebhookDelivery, error) {
+	if err := w.checkAdmin(userId); err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case "", domain.WebhookPending, domain.WebhookSending, domain.WebhookDelivered, domain.WebhookDead:
+	default:
+		return nil, errors.New("status must be pending, sending, delivered or dead")
+	}
+
+	if _, err := w.repo.GetWebhook(webhookId); err != nil {
+		return nil, err
+	}
+	return w.repo.GetDeliveries(webhookId, status, MaxWebhookDeliveries)
+}
+
+func (w *WebhookService) RetryDelivery(userId, webhookId, id string) error {
+	if err := w.checkAdmin(userId); err != nil {
+		return err
+	}
+	return w.repo.RetryDelivery(id, webhookId, time.Now().UTC())
+}
+
+// Dispatch queues an event for every webhook subscribed to it. The deliveries
+// are stored and sent in the background, so neither a slow database nor a slow
+// receiver holds up the request.
+func (w *WebhookService) Dispatch(eventType, conversationId string, data interface{}) {
+	if !slices.Contains(WebhookEvents, eventType) {
+		return
+	}
+
+	// encoded right away, so later changes by the caller do not leak in
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("dispatch %s: %v", eventType, err)
+		return
+	}
+
+	event := webhookEvent{
+		eventType:      eventType,
+		conversationId: conversationId,
+		messageId:      webhookMessageId(data),
+		data:           encoded,
+		createdAt:      time.Now().UTC(),
+	}
+
+	select {
+	case w.queue <- event:
+	default:
+		// a full queue slows the request down rather than losing the event
+		w.store(event, 1)
+	}
+}
+
+// store turns an event into stored deliveries, retrying a failing database
+// with a doubling delay. From there the dispatcher claims and sends them.
+func (w *WebhookService) store(event webhookEvent, attempts int) {
+	var (
+		deliveries []domain.WebhookDelivery
+		err        error
+	)
+
+	delay := webhookStoreRetryDelay
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			log.Printf("dispatch %s: %v", event.eventType, err)
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		// deliveries keep their ids across retries, so a partly stored batch is
+		// not stored twice
+		if deliveries == nil {
+			if deliveries, err = w.deliveriesFor(event); err != nil {
+				continue
+			}
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+		if err = w.repo.AddDeliveries(deliveries); err == nil {
+			return
+		}
+	}
+	log.Printf("dispatch %s: giving up after %d attempts: %v", event.eventType, attempts, err)
+}
+
+// deliveriesFor builds a delivery for every webhook subscribed to the event.
+func (w *WebhookService) deliveriesFor(event webhookEvent) ([]domain.WebhookDelivery, error) {
+	webhooks, err := w.repo.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	deliveries := []domain.WebhookDelivery{}
+	for _, webhook := range webhooks {
+		if !slices.Contains(webhook.Events, event.eventType) {
+			continue
+		}
+
+		id := uuid.New().String()
+		payload, err := json.Marshal(webhookPayload{
+			Id:             id,
+			Event:          event.eventType,
+			ConversationId: event.conversationId,
+			Data:           event.data,
+			CreatedAt:      event.createdAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, domain.WebhookDelivery{
+			Id:            id,
+			WebhookId:     webhook.Id,
+			Event:         event.eventType,
+			MessageId:     event.messageId,
+			Payload:       string(payload),
+			Status:        domain.WebhookPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+	return deliveries, nil
+}
+
+// StartDispatcher stores the dispatched events and polls the persisted delivery
+// queue, so deliveries queued before a restart are still sent. Claims are
+// leased per instance.
+func (w *WebhookService) StartDispatcher(interval time.Duration) {
+	go func() {
+		for event := range w.queue {
+			w.store(event, webhookStoreAttempts)
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := w.DeliverDue(); err != nil {
+				log.Printf("deliver webhooks: %v", err)
+			} else if count > 0 {
+				log.Printf("delivered %d webhooks", count)
+			}
+		}
+	}()
+}
+
+// StartPruner regularly drops deliveries that are older than the retention
+// and no longer waiting to be sent.
+func (w *WebhookService) StartPruner(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := w.PruneDeliveries(); err != nil {
+				log.Printf("prune webhook deliveries: %v", err)
+			} else if count > 0 {
+				log.Printf("pruned %d webhook deliveries", count)
+			}
+		}
+	}()
+}
+
+func (w *WebhookService) PruneDeliveries() (int, error) {
+	return w.repo.PruneDeliveries(time.Now().UTC().Add(-w.retention))
+}
+
+// DropMessageDeliveries forgets the deliveries not yet sent for messages that
+// expired or were purged, so their content does not outlive them.
+func (w *WebhookService) DropMessageDeliveries(messageIds []string) error {
+	return w.repo.DeleteMessageDeliveries(messageIds)
+}
+
+func (w *WebhookService) DeliverDue() (int, error) {
+	claimed, err := w.repo.ClaimDueDeliveries(time.Now().UTC(), w.instanceId, webhookLease, webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(claimed) == 0 {
+		return 0, nil
+	}
+
+	webhooks, err := w.repo.GetWebhooks()
+	if err != nil {
+		return 0, err
+	}
+
+	byId := make(map[string]*domain.Webhook, len(webhooks))
+	for _, webhook := range webhooks {
+		byId[webhook.Id] = webhook
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+	)
+	for _, delivery := range claimed {
+		wg.Add(1)
+		go func(delivery *domain.WebhookDelivery) {
+			defer wg.Done()
+
+			if w.deliver(byId[delivery.WebhookId], delivery) {
+				mu.Lock()
+				delivered++
+				mu.Unlock()
+			}
+		}(delivery)
+	}
+	wg.Wait()
+	return delivered, nil
+}
+
+func (w *WebhookService) deliver(webhook *domain.Webhook, delivery *domain.WebhookDelivery) bool {
+	delivery.Attempts++
+	delivery.ResponseStatus = 0
+	delivery.Error = ""
+
+	if webhook == nil {
+		delivery.Error = "webhook was deleted"
+	} else {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers := map[string]string{
+			"X-Webhook-Id":        webhook.Id,
+			"X-Webhook-Event":     delivery.Event,
+			"X-Webhook-Delivery":  delivery.Id,
+			"X-Webhook-Timestamp": timestamp,
+			"X-Webhook-Signature": "sha256=" + signWebhook(webhook.Secret, timestamp, delivery.Payload),
+		}
+
+		status, err := w.sender.Send(webhook.Url, headers, []byte(delivery.Payload))
+		delivery.ResponseStatus = status
+		if err != nil {
+			delivery.Error = err.Error()
+		} else if status < 200 || status > 299 {
+			delivery.Error = fmt.Sprintf("receiver answered %d", status)
+		}
+	}
+
+	now := time.Now().UTC()
+	delivery.UpdatedAt = now
+	switch {
+	case delivery.Error == "":
+		// the receiver has the event, so its content is not kept around
+		delivery.Status = domain.WebhookDelivered
+		delivery.DeliveredAt = &now
+		delivery.Payload = ""
+	case webhook == nil || delivery.Attempts >= w.attempts:
+		delivery.Status = domain.WebhookDead
+	default:
+		delivery.Status = domain.WebhookPending
+		delivery.NextAttemptAt = now.Add(w.retryDelay(delivery.Attempts))
+	}
+
+	if err := w.repo.FinishDelivery(*delivery, w.instanceId); err != nil {
+		log.Printf("finish webhook delivery %s: %v", delivery.Id, err)
+		return false
+	}
+	return delivery.Status == domain.WebhookDelivered
+}
+
+// webhookMessageId returns the message an event is about, if any.
+func webhookMessageId(data interface{}) string {
+	switch data := data.(type) {
+	case *domain.Message:
+		return data.Id
+	case domain.Message:
+		return data.Id
+	case map[string]string:
+		return data["_id"]
+	}
+	return ""
+}
+
+// retryDelay doubles the backoff after every failed attempt, up to a cap.
+func (w *WebhookService) retryDelay(attempts int) time.Duration {
+	delay := w.backoff
+	for i := 1; i < attempts && delay < maxWebhookBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxWebhookBackoff)
+}
+
+func (w *WebhookService) checkAdmin(userId string) error {
+	if !w.admins[userId] {
+		return ErrNotAdmin
+	}
+	return nil
+}
+
+// signWebhook signs the timestamp together with the body, so a captured
+// request cannot be replayed later with a fresh timestamp.
+func signWebhook(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (m *MessangerService) SetWebhooks(webhooks *WebhookService) {
+	m.webhooks = webhooks
+}
+
+func (u *UserService) SetWebhooks(webhooks *WebhookService) {
+	u.webhooks = webhooks
+}
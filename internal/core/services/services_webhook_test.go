@@ -0,0 +1,91 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"messenger/internal/adapters/webhook"
+	"messenger/internal/core/domain"
+	"messenger/internal/core/ports"
+)
+
+type stubWebhookRepository struct {
+	ports.WebhookRepository
+	webhooks   []*domain.Webhook
+	deliveries []domain.WebhookDelivery
+	calls      int
+}
+
+func (s *stubWebhookRepository) GetWebhooks() ([]*domain.Webhook, error) {
+	s.calls++
+	return s.webhooks, nil
+}
+
+func (s *stubWebhookRepository) AddDeliveries(deliveries []domain.WebhookDelivery) error {
+	s.calls++
+	s.deliveries = append(s.deliveries, deliveries...)
+	return nil
+}
+
+func (s *stubWebhookRepository) AddWebhook(hook domain.Webhook) error {
+	s.webhooks = append(s.webhooks, &hook)
+	return nil
+}
+
+func TestDispatchStoresInTheBackground(t *testing.T) {
+	repo := &stubWebhookRepository{webhooks: []*domain.Webhook{
+		{Id: "w1", Events: []string{domain.EventMessageCreated}},
+		{Id: "w2", Events: []string{domain.EventUserRegistered}},
+	}}
+	service := NewWebhookService(repo, webhook.NewHTTPWebhookSender(time.Second))
+
+	message := &domain.Message{Id: "m1", Body: "hello"}
+	service.Dispatch(domain.EventMessageCreated, "c1", message)
+	message.Body = "changed later"
+
+	if repo.calls != 0 {
+		t.Fatalf("dispatch made %d repository calls in the request path", repo.calls)
+	}
+
+	service.store(<-service.queue, 1)
+	if len(repo.deliveries) != 1 {
+		t.Fatalf("stored %d deliveries, want 1", len(repo.deliveries))
+	}
+
+	delivery := repo.deliveries[0]
+	if delivery.WebhookId != "w1" || delivery.MessageId != "m1" || delivery.Status != domain.WebhookPending {
+		t.Errorf("delivery = %+v, want a pending delivery of m1 to w1", delivery)
+	}
+
+	var payload struct {
+		Id   string         `json:"id"`
+		Data domain.Message `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(delivery.Payload), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Id != delivery.Id || payload.Data.Body != "hello" {
+		t.Errorf("payload = %+v, want the message as dispatched", payload)
+	}
+}
+
+func TestCreateWebhookRefusesPrivateUrls(t *testing.T) {
+	repo := &stubWebhookRepository{}
+	service := NewWebhookService(repo, webhook.NewHTTPWebhookSender(time.Second))
+	service.SetAdmins([]string{"admin"})
+
+	for _, url := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://169.254.169.254/"} {
+		_, err := service.CreateWebhook("admin", domain.Webhook{Url: url, Events: []string{domain.EventMessageCreated}})
+		if err == nil {
+			t.Errorf("webhook to %s created", url)
+		}
+	}
+	if len(repo.webhooks) != 0 {
+		t.Errorf("%d webhooks stored", len(repo.webhooks))
+	}
+
+	if _, err := service.CreateWebhook("admin", domain.Webhook{Url: "https://hooks.example.com/in", Events: []string{domain.EventMessageCreated}}); err != nil {
+		t.Errorf("webhook to a public url refused: %v", err)
+	}
+}